@@ -0,0 +1,205 @@
+package dblist
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// BackupEventKind is a kind of a change of a backup file.
+type BackupEventKind string
+
+// Kinds of backup events.
+const (
+	NewBackup       BackupEventKind = "new_backup"       // a backup file was created, it may be still written
+	BackupCompleted BackupEventKind = "backup_completed" // a backup file was written or moved into a directory
+	BackupDeleted   BackupEventKind = "backup_deleted"   // a backup file was deleted or moved out of a directory
+	MarkerChanged   BackupEventKind = "marker_changed"   // the uploaded mark of a backup file changed
+)
+
+// BackupEvent is a change of a backup file in a config path.
+type BackupEvent struct {
+	Kind     BackupEventKind `json:"kind"`
+	Path     string          `json:"path"`
+	Name     string          `json:"name"`
+	DBName   string          `json:"dbname"`
+	Suffix   string          `json:"suffix"`
+	Time     time.Time       `json:"time"`     // time in file name, zero if there is none
+	Uploaded bool            `json:"uploaded"` // the uploaded mark of a file, see FileInfoWin.IsUploaded
+}
+
+// backupEventsBuffer lets a reader lag behind a burst of events.
+const backupEventsBuffer = 64
+
+// BackupWatcher reports changes of backup files in directories of config lines.
+// Unlike ConfigWatcher it never drops events, a slow reader delays them.
+// Files of databases that are not in config are ignored.
+// Directories matching a glob path after WatchBackups are not watched.
+type BackupWatcher struct {
+	w              dirWatcher
+	nameTosuffixes map[string][]string
+	events         chan BackupEvent
+	errors         chan error
+	done           chan struct{}
+	stopped        chan struct{}
+	closing        sync.Once
+	uploaded       map[string]bool // full file names to their last known uploaded marks
+}
+
+// WatchBackups starts watching every path of config lines, see GetUniquePaths.
+// An inexistent path is an error.
+func WatchBackups(conf []ConfigLine) (*BackupWatcher, error) {
+	w, err := newDirWatcher()
+	if err != nil {
+		return nil, err
+	}
+	bw := &BackupWatcher{
+		w:              w,
+		nameTosuffixes: GetMapFilenameToSuffixes(conf),
+		events:         make(chan BackupEvent, backupEventsBuffer),
+		errors:         make(chan error, 1),
+		done:           make(chan struct{}),
+		stopped:        make(chan struct{}),
+		uploaded:       make(map[string]bool),
+	}
+	paths := GetUniquePaths(conf)
+	for path := range paths {
+		if err := w.Watch(path); err != nil {
+			w.Close()
+			return nil, err
+		}
+	}
+	// marks are read after watching starts, so no change is lost in between
+	for path, files := range ReadFilesFromPaths(paths) {
+		for _, f := range files {
+			bw.uploaded[filepath.Join(path, f.Name())] = f.IsUploaded()
+		}
+	}
+	go bw.run()
+	return bw, nil
+}
+
+// Events receives changes of backup files.
+// ErrEventsOverflow in Errors means some events were lost and directories must be read again.
+func (bw *BackupWatcher) Events() <-chan BackupEvent {
+	return bw.events
+}
+
+// Errors receives errors of watching, only the latest error is kept for a slow reader.
+// ErrWatcherStopped means no more events come.
+func (bw *BackupWatcher) Errors() <-chan error {
+	return bw.errors
+}
+
+// Close stops watching, it may be called more than once.
+func (bw *BackupWatcher) Close() error {
+	bw.closing.Do(func() { close(bw.done) })
+	err := bw.w.Close()
+	<-bw.stopped
+	return err
+}
+
+func (bw *BackupWatcher) run() {
+	defer close(bw.stopped)
+	events, errs := bw.w.Events(), bw.w.Errors()
+	for {
+		select {
+		case <-bw.done:
+			return
+		case ev, ok := <-events:
+			if !ok {
+				bw.stop(errs)
+				return
+			}
+			for _, be := range bw.backupEvents(ev) {
+				select {
+				case bw.events <- be:
+				case <-bw.done:
+					return
+				}
+			}
+		case err, ok := <-errs:
+			if !ok {
+				bw.stop(errs)
+				return
+			}
+			bw.sendErr(err)
+		}
+	}
+}
+
+// stop reports ErrWatcherStopped with an error that stopped the watcher if there is one.
+func (bw *BackupWatcher) stop(errs <-chan error) {
+	var cause error
+	select {
+	case cause = <-bw.errors: // not read yet
+	default:
+	}
+	select {
+	case err, ok := <-errs:
+		if ok && err != nil {
+			cause = err
+		}
+	default:
+	}
+	err := ErrWatcherStopped
+	if cause != nil && cause != ErrEventsOverflow {
+		err = fmt.Errorf("%w: %v", ErrWatcherStopped, cause)
+	}
+	bw.errors <- err
+}
+
+// sendErr keeps only the latest error for a slow reader.
+func (bw *BackupWatcher) sendErr(err error) {
+	select {
+	case <-bw.errors: // drop the error nobody has read
+	default:
+	}
+	bw.errors <- err
+}
+
+// backupEvents converts a file system event to events of a backup file.
+func (bw *BackupWatcher) backupEvents(ev fsEvent) []BackupEvent {
+	dbname, suffix := GroupFunc(ev.Name, bw.nameTosuffixes)
+	if dbname == "" || suffix == constFileNameHasWrongSuffix {
+		return nil // not a backup file or its database is not in config
+	}
+	t, _ := ExtractTimeFromFilename(ev.Name)
+	be := BackupEvent{Path: ev.Dir, Name: ev.Name, DBName: dbname, Suffix: suffix, Time: t}
+	full := filepath.Join(ev.Dir, ev.Name)
+
+	if ev.Op == fsRemove {
+		be.Kind, be.Uploaded = BackupDeleted, bw.uploaded[full]
+		delete(bw.uploaded, full)
+		return []BackupEvent{be}
+	}
+	fi, err := os.Lstat(full)
+	if err != nil || !fi.Mode().IsRegular() {
+		return nil // already deleted, its removal comes next
+	}
+	fiw, _ := ReadFileInfoWin(full, fi)
+	be.Uploaded = fiw.IsUploaded()
+	prev := bw.uploaded[full] // new files are not uploaded
+	bw.uploaded[full] = be.Uploaded
+
+	switch ev.Op {
+	case fsCreate:
+		be.Kind = NewBackup
+	case fsMovedIn:
+		completed := be
+		be.Kind, completed.Kind = NewBackup, BackupCompleted
+		return []BackupEvent{be, completed}
+	case fsWrite:
+		be.Kind = BackupCompleted
+	case fsAttrib:
+		if prev == be.Uploaded {
+			return nil // attributes other than the mark
+		}
+		be.Kind = MarkerChanged
+	default:
+		return nil
+	}
+	return []BackupEvent{be}
+}
@@ -0,0 +1,129 @@
+package dblist
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchBackups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dblist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	conf := []ConfigLine{{Path: dir, Filename: "zp", Suffix: "-FULL.bak", Days: 1}}
+
+	bw, err := WatchBackups(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bw.Close()
+
+	next := func() BackupEvent {
+		t.Helper()
+		select {
+		case ev := <-bw.Events():
+			return ev
+		case err := <-bw.Errors():
+			t.Fatalf("Errors() = %v", err)
+		case <-time.After(10 * time.Second):
+			t.Fatal("no event")
+		}
+		return BackupEvent{}
+	}
+	want := func(kind BackupEventKind, name string) {
+		t.Helper()
+		ev := next()
+		if ev.Kind != kind || ev.Name != name || ev.DBName != "zp" || ev.Suffix != "-FULL.bak" {
+			t.Errorf("event = %+v, want %s of %s", ev, kind, name)
+		}
+		if wantTime := time.Date(2021, 3, 2, 21, 0, 0, 0, time.UTC); !ev.Time.Equal(wantTime) {
+			t.Errorf("event time = %v, want %v", ev.Time, wantTime)
+		}
+	}
+
+	// other databases and other files are ignored
+	for _, name := range []string{"other_2021-03-02T21-00-00-FULL.bak", "readme.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	written := "zp_2021-03-02T21-00-00-FULL.bak"
+	if err := ioutil.WriteFile(filepath.Join(dir, written), []byte("backup"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	want(NewBackup, written)
+	want(BackupCompleted, written)
+
+	moved := "zp_2021-03-02T21-00-00-001-FULL.bak"
+	if err := ioutil.WriteFile(filepath.Join(dir, "tmp"), []byte("backup"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(dir, "tmp"), filepath.Join(dir, moved)); err != nil {
+		t.Fatal(err)
+	}
+	want(NewBackup, moved)
+	want(BackupCompleted, moved)
+
+	if err := MarkUploaded(filepath.Join(dir, moved)); err == nil { // xattrs may be unsupported
+		want(MarkerChanged, moved)
+	}
+
+	// a change of other attributes is not a marker change
+	if err := os.Chmod(filepath.Join(dir, written), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, written)); err != nil {
+		t.Fatal(err)
+	}
+	want(BackupDeleted, written)
+	if err := bw.Close(); err != nil {
+		t.Errorf("Close() = %v", err)
+	}
+	if err := bw.Close(); err != nil {
+		t.Errorf("second Close() = %v", err)
+	}
+}
+
+// stoppedWatcher is a dirWatcher that failed.
+type stoppedWatcher struct {
+	events chan fsEvent
+	errors chan error
+}
+
+func (w *stoppedWatcher) Watch(dir string) error { return nil }
+func (w *stoppedWatcher) Events() <-chan fsEvent { return w.events }
+func (w *stoppedWatcher) Errors() <-chan error   { return w.errors }
+func (w *stoppedWatcher) Close() error           { return nil }
+
+func TestBackupWatcherStopped(t *testing.T) {
+	w := &stoppedWatcher{events: make(chan fsEvent), errors: make(chan error, 1)}
+	w.errors <- errors.New("bad file descriptor")
+	close(w.events)
+	bw := &BackupWatcher{w: w, events: make(chan BackupEvent), errors: make(chan error, 1),
+		done: make(chan struct{}), stopped: make(chan struct{}), uploaded: make(map[string]bool)}
+	go bw.run()
+	defer bw.Close()
+	// the cause may come before ErrWatcherStopped or with it
+	for cause := false; ; {
+		select {
+		case err := <-bw.Errors():
+			cause = cause || strings.Contains(err.Error(), "bad file descriptor")
+			if !errors.Is(err, ErrWatcherStopped) {
+				continue
+			}
+			if !cause {
+				t.Errorf("Errors() = %v without its cause", err)
+			}
+			return
+		case <-time.After(5 * time.Second):
+			t.Fatal("no error of a stopped watcher")
+		}
+	}
+}
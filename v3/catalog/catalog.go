@@ -0,0 +1,327 @@
+// Package catalog keeps history of backup files in an embedded bbolt database.
+// A scan of directories shows only existing files, the catalog remembers
+// every file ever seen with its size, checksum, upload state and times it was seen and deleted.
+package catalog
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zavla/dblist/v3"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	bucketFiles   = []byte("files")   // path \x00 name [\x00 deleted at] to json Record
+	bucketDBNames = []byte("dbnames") // dbname \x00 key in files to nothing
+)
+
+// Record is a file seen in a backup directory.
+type Record struct {
+	Path       string    `json:"path"`
+	Name       string    `json:"name"`
+	DBName     string    `json:"dbname"`
+	Suffix     string    `json:"suffix"`
+	Size       int64     `json:"size"`
+	Modtime    time.Time `json:"modtime"`
+	BackupTime time.Time `json:"backup_time"`        // time in file name, see dblist.BackupTimeIn
+	Checksum   string    `json:"checksum,omitempty"` // hex sha256, empty if not computed
+	Uploaded   bool      `json:"uploaded"`
+	FirstSeen  time.Time `json:"first_seen"`
+	LastSeen   time.Time `json:"last_seen"`
+	DeletedAt  time.Time `json:"deleted_at"` // zero while the file exists
+}
+
+// Catalog is a history of backup files.
+type Catalog struct {
+	db *bolt.DB
+	// Checksum computes checksums of new and changed files, no checksums if nil.
+	Checksum func(filename string) (string, error)
+	// Location is a time zone of times in file names, local if nil, see dblist.Config.Location.
+	Location *time.Location
+}
+
+// Open opens or creates a catalog file.
+func Open(filename string) (*Catalog, error) {
+	db, err := bolt.Open(filename, 0644, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("can't open catalog %s: %w", filename, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketFiles, bucketDBNames, bucketDirs} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("can't open catalog %s: %w", filename, err)
+	}
+	return &Catalog{db: db}, nil
+}
+
+// Close closes the catalog file.
+func (c *Catalog) Close() error {
+	return c.db.Close()
+}
+
+// FileSHA256 returns hex sha256 of a file, it is used as Catalog.Checksum.
+func FileSHA256(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// recordKey is a key of a record in files bucket.
+// Records of deleted files have deletion time in their keys, so a file with the same name gets a new record.
+func recordKey(r *Record) []byte {
+	key := r.Path + "\x00" + r.Name
+	if !r.DeletedAt.IsZero() {
+		key += "\x00" + r.DeletedAt.Format(time.RFC3339Nano)
+	}
+	return []byte(key)
+}
+
+// Update records files of a scan made by dblist.ReadFilesFromPaths.
+// New files are added, seen files get LastSeen, upload state and size,
+// files of scanned paths that are absent from the scan get DeletedAt.
+// Paths absent from filesByPath are not changed, they may be unreadable now.
+// A file that appears again after deletion starts a new record.
+// Files whose checksum failed are recorded without it and reported in the returned error.
+// Checksums are computed before the catalog is locked for writing.
+// now is usually time.Now().
+func (c *Catalog) Update(filesByPath map[string][]dblist.FileInfoWin, nameTosuffixes map[string][]string, now time.Time) error {
+	now = now.UTC()
+	sums, errs, err := c.checksums(filesByPath)
+	if err != nil {
+		return fmt.Errorf("can't update catalog: %w", err)
+	}
+	err = c.db.Update(func(tx *bolt.Tx) error {
+		files, dbnames := tx.Bucket(bucketFiles), tx.Bucket(bucketDBNames)
+		for path, infos := range filesByPath {
+			seen := make(map[string]bool, len(infos))
+			for _, fi := range infos {
+				seen[fi.Name()] = true
+				r := &Record{Path: path, Name: fi.Name()}
+				if b := files.Get(recordKey(r)); b != nil {
+					if err := json.Unmarshal(b, r); err != nil {
+						return err
+					}
+				}
+				changed := r.Size != fi.Size() || !r.Modtime.Equal(fi.ModTime())
+				if r.FirstSeen.IsZero() {
+					dbname, suffix := dblist.GroupFunc(fi.Name(), nameTosuffixes)
+					if dbname == "" {
+						dbname = dblist.ExtractDBName(fi.Name())
+					}
+					*r = Record{Path: path, Name: fi.Name(), DBName: dbname, Suffix: suffix,
+						BackupTime: dblist.BackupTimeIn(fi, c.Location), FirstSeen: now}
+					changed = true
+				}
+				r.Size, r.Modtime, r.Uploaded, r.LastSeen = fi.Size(), fi.ModTime(), fi.IsUploaded(), now
+				if changed && c.Checksum != nil {
+					r.Checksum = sums[filepath.Join(path, fi.Name())] // empty if the file changed after checksums
+				}
+				if err := put(files, dbnames, r); err != nil {
+					return err
+				}
+			}
+
+			// live records of files absent from the scan are moved to keys with deletion time
+			deleted := []*Record{}
+			err := forEachLive(files, path, func(r *Record) error {
+				if !seen[r.Name] {
+					r.DeletedAt = now
+					deleted = append(deleted, r)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			for _, r := range deleted {
+				live := &Record{Path: r.Path, Name: r.Name}
+				if err := files.Delete(recordKey(live)); err != nil {
+					return err
+				}
+				if err := dbnames.Delete(dbnameKey(r.DBName, recordKey(live))); err != nil {
+					return err
+				}
+				if err := put(files, dbnames, r); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("can't update catalog: %w", err)
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("no checksums for some files: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// checksums computes checksums of new and changed files of a scan, it returns full file names to checksums.
+// Files whose checksum failed are reported in errs.
+func (c *Catalog) checksums(filesByPath map[string][]dblist.FileInfoWin) (sums map[string]string, errs []string, err error) {
+	sums = make(map[string]string)
+	if c.Checksum == nil {
+		return sums, nil, nil
+	}
+	filenames := []string{}
+	err = c.db.View(func(tx *bolt.Tx) error {
+		files := tx.Bucket(bucketFiles)
+		for path, infos := range filesByPath {
+			for _, fi := range infos {
+				r := &Record{Path: path, Name: fi.Name()}
+				if b := files.Get(recordKey(r)); b != nil {
+					if err := json.Unmarshal(b, r); err != nil {
+						return err
+					}
+				}
+				if r.FirstSeen.IsZero() || r.Size != fi.Size() || !r.Modtime.Equal(fi.ModTime()) {
+					filenames = append(filenames, filepath.Join(path, fi.Name()))
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, filename := range filenames {
+		sum, err := c.Checksum(filename)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		sums[filename] = sum
+	}
+	return sums, errs, nil
+}
+
+// forEachLive calls f for records of existing files in a path.
+func forEachLive(files *bolt.Bucket, path string, f func(r *Record) error) error {
+	cur := files.Cursor()
+	prefix := []byte(path + "\x00")
+	for k, v := cur.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cur.Next() {
+		r := &Record{}
+		if err := json.Unmarshal(v, r); err != nil {
+			return err
+		}
+		if r.DeletedAt.IsZero() {
+			if err := f(r); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func dbnameKey(dbname string, key []byte) []byte {
+	return append([]byte(dbname+"\x00"), key...)
+}
+
+// put stores a record and its index key.
+func put(files, dbnames *bolt.Bucket, r *Record) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	key := recordKey(r)
+	if err := files.Put(key, b); err != nil {
+		return err
+	}
+	return dbnames.Put(dbnameKey(r.DBName, key), []byte{})
+}
+
+// Query selects records of a catalog.
+type Query struct {
+	DBName string    // all databases if empty
+	Path   string    // all paths if empty
+	From   time.Time // files that existed at some time in From..To, zero means unbounded
+	To     time.Time
+}
+
+// matches reports whether a record satisfies a query.
+func (q Query) matches(r *Record) bool {
+	switch {
+	case q.DBName != "" && r.DBName != q.DBName:
+		return false
+	case q.Path != "" && r.Path != q.Path:
+		return false
+	case !q.To.IsZero() && r.FirstSeen.After(q.To):
+		return false
+	case !q.From.IsZero() && !r.DeletedAt.IsZero() && r.DeletedAt.Before(q.From):
+		return false
+	}
+	return true
+}
+
+// Find returns records that match a query ordered by path, name and FirstSeen.
+func (c *Catalog) Find(q Query) ([]Record, error) {
+	ret := []Record{}
+	err := c.db.View(func(tx *bolt.Tx) error {
+		files := tx.Bucket(bucketFiles)
+		add := func(v []byte) error {
+			r := Record{}
+			if err := json.Unmarshal(v, &r); err != nil {
+				return err
+			}
+			if q.matches(&r) {
+				ret = append(ret, r)
+			}
+			return nil
+		}
+		if q.DBName == "" {
+			return files.ForEach(func(k, v []byte) error { return add(v) })
+		}
+		cur := tx.Bucket(bucketDBNames).Cursor()
+		prefix := []byte(q.DBName + "\x00")
+		for k, _ := cur.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cur.Next() {
+			if v := files.Get(k[len(prefix):]); v != nil {
+				if err := add(v); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't read catalog: %w", err)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Path != ret[j].Path {
+			return ret[i].Path < ret[j].Path
+		}
+		if ret[i].Name != ret[j].Name {
+			return ret[i].Name < ret[j].Name
+		}
+		return ret[i].FirstSeen.Before(ret[j].FirstSeen)
+	})
+	return ret, nil
+}
+
+// At returns records of files that existed at time t.
+func (c *Catalog) At(t time.Time) ([]Record, error) {
+	return c.Find(Query{From: t, To: t})
+}
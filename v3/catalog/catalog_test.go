@@ -0,0 +1,174 @@
+package catalog
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zavla/dblist/v3"
+	bolt "go.etcd.io/bbolt"
+)
+
+// testFile is os.FileInfo of a backup file.
+type testFile struct {
+	name string
+	size int64
+}
+
+func (f testFile) Name() string       { return f.name }
+func (f testFile) Size() int64        { return f.size }
+func (f testFile) Mode() os.FileMode  { return 0644 }
+func (f testFile) ModTime() time.Time { return time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC) }
+func (f testFile) IsDir() bool        { return false }
+func (f testFile) Sys() interface{}   { return nil }
+
+func scanOf(path string, names ...string) map[string][]dblist.FileInfoWin {
+	files := []dblist.FileInfoWin{}
+	for _, name := range names {
+		files = append(files, dblist.FileInfoWin{FileInfo: testFile{name, int64(len(name))}, WinAttr: 0x20})
+	}
+	return map[string][]dblist.FileInfoWin{path: files}
+}
+
+func names(records []Record) []string {
+	ret := []string{}
+	for _, r := range records {
+		ret = append(ret, r.Name)
+	}
+	return ret
+}
+
+func TestCatalog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dblist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c, err := Open(filepath.Join(dir, "catalog.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.Checksum = func(filename string) (string, error) { return "sum of " + filepath.Base(filename), nil }
+
+	nameTosuffixes := dblist.GetMapFilenameToSuffixes([]dblist.ConfigLine{{Filename: "buh", Suffix: "-FULL.bak"}})
+	full1 := "buh_2021-03-01T21-00-00-001-FULL.bak"
+	full2 := "buh_2021-03-08T21-00-00-001-FULL.bak"
+	zp := "zp_2021-03-01T21-00-00-001-FULL.bak"
+	march := func(day int) time.Time { return time.Date(2021, 3, day, 23, 0, 0, 0, time.UTC) }
+
+	if err := c.Update(scanOf("g:/ShebB", full1, zp), nameTosuffixes, march(1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Update(scanOf("g:/ShebB", full1, full2), nameTosuffixes, march(8)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Update(scanOf("g:/ShebB", full2), nameTosuffixes, march(9)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Update(scanOf("g:/ShebB", full1, full2), nameTosuffixes, march(10)); err != nil {
+		t.Fatal(err) // full1 is restored from an archive
+	}
+
+	tests := []struct {
+		name string
+		q    Query
+		want []string
+	}{
+		{"all", Query{}, []string{full1, full1, full2, zp}},
+		{"march 3rd", Query{From: march(3), To: march(3)}, []string{full1, zp}},
+		{"march 9th", Query{From: march(9).Add(time.Hour), To: march(9).Add(time.Hour)}, []string{full2}},
+		{"by dbname", Query{DBName: "zp"}, []string{zp}},
+		{"by dbname and range", Query{DBName: "buh", From: march(9).Add(time.Hour), To: march(10)}, []string{full1, full2}},
+		{"other path", Query{Path: "g:/other"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.Find(tt.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if g := names(got); !reflect.DeepEqual(g, tt.want) {
+				t.Errorf("Find(%+v) = %v, want %v", tt.q, g, tt.want)
+			}
+		})
+	}
+
+	got, err := c.At(march(3))
+	if err != nil || len(got) != 2 {
+		t.Fatalf("At() = %+v, %v, want 2 records", got, err)
+	}
+	r := got[0]
+	if r.DBName != "buh" || r.Suffix != "-FULL.bak" || r.Checksum != "sum of "+full1 || !r.FirstSeen.Equal(march(1)) ||
+		!r.DeletedAt.Equal(march(9)) || r.Uploaded || r.Size != int64(len(full1)) {
+		t.Errorf("At() record = %+v", r)
+	}
+	if zpr := got[1]; zpr.DBName != "zp" || zpr.Suffix != "" || !zpr.DeletedAt.Equal(march(8)) {
+		t.Errorf("At() record of not configured database = %+v", zpr)
+	}
+}
+
+func TestUpdateChecksumUnlocked(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dblist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c, err := Open(filepath.Join(dir, "catalog.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	// a checksum of a big backup must not block other writers of the catalog
+	c.Checksum = func(filename string) (string, error) {
+		written := make(chan error, 1)
+		go func() { written <- c.db.Update(func(tx *bolt.Tx) error { return nil }) }()
+		select {
+		case err := <-written:
+			return "sum", err
+		case <-time.After(5 * time.Second):
+			return "", errors.New("catalog is locked during checksum")
+		}
+	}
+	if err := c.Update(scanOf("g:/ShebB", "buh_2021-03-01T21-00-00-001-FULL.bak"), nil, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.Find(Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Checksum != "sum" {
+		t.Errorf("Find() = %+v, want a record with checksum", got)
+	}
+}
+
+func TestUpdateLocation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dblist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c, err := Open(filepath.Join(dir, "catalog.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if c.Location, err = time.LoadLocation("Europe/Moscow"); err != nil {
+		t.Skip(err)
+	}
+	// a file without time in its name has its modification time in config time zone
+	if err := c.Update(scanOf("g:/ShebB", "buh_FULL.bak"), nil, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.Find(Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2021, 8, 1, 3, 0, 0, 0, time.UTC); len(got) != 1 || !got[0].BackupTime.Equal(want) {
+		t.Errorf("Find() = %+v, want BackupTime %v", got, want)
+	}
+}
@@ -0,0 +1,175 @@
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/zavla/dblist/v3"
+	bolt "go.etcd.io/bbolt"
+)
+
+var bucketDirs = []byte("dirs") // path to json dirState
+
+// DefaultFullScanInterval is how often ReadFilesFromPaths reads every file of a directory.
+const DefaultFullScanInterval = 24 * time.Hour
+
+// racyWindow is a precision of file system times.
+// Directories and files modified this close to a scan may have changed after it.
+const racyWindow = 2 * time.Second
+
+// dirState is a directory as it was at the last scan.
+type dirState struct {
+	Modtime    time.Time `json:"modtime"`
+	ScannedAt  time.Time `json:"scanned_at"`
+	FullScanAt time.Time `json:"full_scan_at"`
+}
+
+// ScanOptions tune ReadFilesFromPaths.
+type ScanOptions struct {
+	// FullScanInterval is DefaultFullScanInterval if zero.
+	// A full scan reads upload marks of every file.
+	FullScanInterval time.Duration
+}
+
+// ReadFilesFromPaths is an incremental dblist.ReadFilesFromPaths.
+// Files of a directory are taken from the catalog if the directory modification time
+// is the same as at the previous scan, names are read otherwise.
+// Every file is stat'ed, so a file overwritten in place gets its size.
+// Upload marks of new files, changed files and files modified near the previous scan are read,
+// so a backup that was being written gets its final state.
+// Upload marks of other files are read only if they may have changed: on linux if ctime of a file
+// is near or after the previous scan, on windows attributes come with the stat.
+// Every directory is read completely once in opts.FullScanInterval.
+// The catalog is updated with the scan, see Update.
+// Unreadable directories are logged and skipped like in dblist.ReadFilesFromPaths.
+func (c *Catalog) ReadFilesFromPaths(uniquefolders map[string]int, nameTosuffixes map[string][]string, opts ScanOptions) (map[string][]dblist.FileInfoWin, error) {
+	if opts.FullScanInterval == 0 {
+		opts.FullScanInterval = DefaultFullScanInterval
+	}
+	now := time.Now().UTC()
+	retmap := make(map[string][]dblist.FileInfoWin)
+	states := make(map[string]dirState)
+	for uf := range uniquefolders {
+		fullpath, _ := filepath.Abs(uf)
+		dirfi, err := os.Stat(fullpath)
+		if err != nil {
+			log.Printf("skipping directory %s, %s\r\n", fullpath, err)
+			continue
+		}
+		state, live, err := c.dirState(uf)
+		if err != nil {
+			return nil, err
+		}
+
+		var files []dblist.FileInfoWin
+		if state.FullScanAt.IsZero() || now.Sub(state.FullScanAt) >= opts.FullScanInterval {
+			var ok bool
+			if files, ok = dblist.ReadFilesFromPaths(map[string]int{uf: 1})[uf]; !ok {
+				continue // logged by dblist.ReadFilesFromPaths
+			}
+			state.FullScanAt = now
+		} else if files, err = readChanged(fullpath, dirfi, state, live); err != nil {
+			log.Printf("skipping directory %s, %s\r\n", fullpath, err)
+			continue
+		}
+		retmap[uf] = files
+		state.Modtime, state.ScannedAt = dirfi.ModTime(), now
+		states[uf] = state
+	}
+
+	if err := c.Update(retmap, nameTosuffixes, now); err != nil {
+		return retmap, err
+	}
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketDirs)
+		for path, state := range states {
+			v, err := json.Marshal(state)
+			if err != nil {
+				return err
+			}
+			if err := b.Put([]byte(path), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return retmap, fmt.Errorf("can't update catalog: %w", err)
+	}
+	return retmap, nil
+}
+
+// dirState returns the state of a directory and its live records.
+func (c *Catalog) dirState(path string) (dirState, map[string]*Record, error) {
+	state := dirState{}
+	live := make(map[string]*Record)
+	err := c.db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket(bucketDirs); b != nil {
+			if v := b.Get([]byte(path)); v != nil {
+				if err := json.Unmarshal(v, &state); err != nil {
+					return err
+				}
+			}
+		}
+		return forEachLive(tx.Bucket(bucketFiles), path, func(r *Record) error {
+			live[r.Name] = r
+			return nil
+		})
+	})
+	if err != nil {
+		return state, nil, fmt.Errorf("can't read catalog: %w", err)
+	}
+	return state, live, nil
+}
+
+// readChanged returns files of a directory reading from disk only what may have changed since the previous scan.
+func readChanged(fullpath string, dirfi os.FileInfo, state dirState, live map[string]*Record) ([]dblist.FileInfoWin, error) {
+	names := make([]string, 0, len(live))
+	if !dirfi.ModTime().Equal(state.Modtime) || state.Modtime.After(state.ScannedAt.Add(-racyWindow)) {
+		d, err := os.Open(fullpath)
+		if err != nil {
+			return nil, err
+		}
+		names, err = d.Readdirnames(-1)
+		d.Close()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		for name := range live {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names) // the order of ioutil.ReadDir
+
+	ret := make([]dblist.FileInfoWin, 0, len(names))
+	for _, name := range names {
+		if dblist.ExtractDBName(name) == "" {
+			continue
+		}
+		fullFilename := filepath.Join(fullpath, name)
+		fi, err := os.Lstat(fullFilename)
+		if err != nil {
+			continue // deleted after reading the directory
+		}
+		var fiw dblist.FileInfoWin
+		if r, ok := live[name]; ok && r.Modtime.Before(state.ScannedAt.Add(-racyWindow)) &&
+			fi.Size() == r.Size && fi.ModTime().Equal(r.Modtime) {
+			fiw, err = readMark(fullFilename, fi, r, state.ScannedAt)
+		} else {
+			fiw, err = dblist.ReadFileInfoWin(fullFilename, fi)
+		}
+		if err != nil {
+			log.Printf("%s\r\n", err)
+		}
+		if fiw.FileInfo != nil {
+			ret = append(ret, fiw)
+		}
+	}
+	return ret, nil
+}
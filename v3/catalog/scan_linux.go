@@ -0,0 +1,24 @@
+package catalog
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/zavla/dblist/v3"
+)
+
+// readMark returns a known file with its upload mark.
+// Setting the 'uploaded' xattr changes ctime of a file, so the xattr is read only if ctime
+// is near or after the previous scan, the mark of the record is used otherwise.
+func readMark(fullFilename string, fi os.FileInfo, r *Record, scannedAt time.Time) (dblist.FileInfoWin, error) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok || !time.Unix(st.Ctim.Unix()).Before(scannedAt.Add(-racyWindow)) {
+		return dblist.ReadFileInfoWin(fullFilename, fi)
+	}
+	attr := uint32(0x20)
+	if r.Uploaded {
+		attr = 0
+	}
+	return dblist.FileInfoWin{FileInfo: fi, WinAttr: attr}, nil
+}
@@ -0,0 +1,102 @@
+package catalog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zavla/dblist/v3"
+)
+
+// sameFiles compares scans by names, sizes, modification times and upload marks.
+func sameFiles(t *testing.T, got, want map[string][]dblist.FileInfoWin) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d paths, want %d", len(got), len(want))
+	}
+	for path, wantFiles := range want {
+		gotFiles := got[path]
+		if len(gotFiles) != len(wantFiles) {
+			t.Errorf("path %s has %d files, want %d", path, len(gotFiles), len(wantFiles))
+			continue
+		}
+		for i, w := range wantFiles {
+			g := gotFiles[i]
+			if g.Name() != w.Name() || g.Size() != w.Size() || !g.ModTime().Equal(w.ModTime()) || g.IsUploaded() != w.IsUploaded() {
+				t.Errorf("file %d = %s %d %v %v, want %s %d %v %v", i,
+					g.Name(), g.Size(), g.ModTime(), g.IsUploaded(), w.Name(), w.Size(), w.ModTime(), w.IsUploaded())
+			}
+		}
+	}
+}
+
+func TestReadFilesFromPaths(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dblist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	backups := filepath.Join(dir, "backups")
+	if err := os.Mkdir(backups, 0755); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	write := func(name, content string) {
+		filename := filepath.Join(backups, name)
+		if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(filename, past, past); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("buh_2021-08-01T21-00-05-001-FULL.bak", "1")
+	write("buh_2021-08-02T21-00-05-001-FULL.bak", "2")
+	write("readme.txt", "")
+	if err := os.Chtimes(backups, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Open(filepath.Join(dir, "catalog.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	paths := map[string]int{backups: 1, filepath.Join(dir, "absent"): 1}
+	nameTosuffixes := dblist.GetMapFilenameToSuffixes([]dblist.ConfigLine{{Filename: "buh", Suffix: "-FULL.bak"}})
+	scan := func(opts ScanOptions) map[string][]dblist.FileInfoWin {
+		got, err := c.ReadFilesFromPaths(paths, nameTosuffixes, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	sameFiles(t, scan(ScanOptions{}), dblist.ReadFilesFromPaths(paths)) // full scan
+
+	os.Remove(filepath.Join(backups, "buh_2021-08-01T21-00-05-001-FULL.bak"))
+	write("buh_2021-08-03T21-00-05-001-FULL.bak", "3")
+	sameFiles(t, scan(ScanOptions{}), dblist.ReadFilesFromPaths(paths)) // names are read
+
+	// a file overwritten in place doesn't change the directory
+	write("buh_2021-08-02T21-00-05-001-FULL.bak", "22")
+	got := scan(ScanOptions{})
+	if f := got[backups][0]; f.Size() != 2 {
+		t.Errorf("incremental scan read file %s of size %d, want the new size", f.Name(), f.Size())
+	}
+	sameFiles(t, scan(ScanOptions{FullScanInterval: time.Nanosecond}), dblist.ReadFilesFromPaths(paths))
+
+	// an upload mark doesn't change modification times, an incremental scan reads it anyway
+	if err := dblist.MarkUploaded(filepath.Join(backups, "buh_2021-08-03T21-00-05-001-FULL.bak")); err == nil {
+		if f := scan(ScanOptions{})[backups][1]; !f.IsUploaded() {
+			t.Errorf("incremental scan didn't read the upload mark of %s", f.Name())
+		}
+	}
+
+	records, err := c.Find(Query{DBName: "buh"})
+	if err != nil || len(records) != 3 {
+		t.Errorf("Find() = %+v, %v, want 3 records", records, err)
+	}
+}
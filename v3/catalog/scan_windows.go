@@ -0,0 +1,18 @@
+package catalog
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/zavla/dblist/v3"
+)
+
+// readMark returns a known file with its upload mark.
+// Attributes of a file come with its os.FileInfo, so no more reads are needed.
+func readMark(fullFilename string, fi os.FileInfo, r *Record, scannedAt time.Time) (dblist.FileInfoWin, error) {
+	if d, ok := fi.Sys().(*syscall.Win32FileAttributeData); ok {
+		return dblist.FileInfoWin{FileInfo: fi, WinAttr: d.FileAttributes}, nil
+	}
+	return dblist.ReadFileInfoWin(fullFilename, fi)
+}
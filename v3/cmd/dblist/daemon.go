@@ -0,0 +1,280 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/zavla/dblist/v3"
+)
+
+// daemonJob is a job of the daemon, jobs due at the same time run in the order of daemonJobs.
+type daemonJob struct {
+	name     string
+	schedule func(d dblist.DaemonConfig) string
+	run      func(d *daemon) error
+}
+
+var daemonJobs = []daemonJob{
+	{"scan", func(c dblist.DaemonConfig) string { return c.Scan }, (*daemon).scan},
+	{"verify", func(c dblist.DaemonConfig) string { return c.Verify }, (*daemon).verify},
+	{"report", func(c dblist.DaemonConfig) string { return c.Report }, (*daemon).report},
+	{"sync", func(c dblist.DaemonConfig) string { return c.Sync }, (*daemon).sync},
+	{"upload", func(c dblist.DaemonConfig) string { return c.Upload }, (*daemon).upload},
+	{"prune", func(c dblist.DaemonConfig) string { return c.Prune }, (*daemon).prune},
+}
+
+// daemonState is kept in -state file between runs of the daemon.
+type daemonState struct {
+	Jobs map[string]*jobState `json:"jobs"`
+}
+
+type jobState struct {
+	LastRun time.Time `json:"last_run"` // wall clock in config time zone like times in file names
+	Error   string    `json:"error,omitempty"`
+}
+
+// daemon runs jobs sharing one config and the latest scan.
+type daemon struct {
+	opts    *options
+	cfg     *dblist.Config
+	w       io.Writer // output of jobs
+	log     *log.Logger
+	state   daemonState
+	started time.Time
+}
+
+func cmdDaemon(opts *options, args []string, w io.Writer) error {
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+	return runDaemon(opts, w, stop)
+}
+
+// runDaemon runs jobs until something is received from stop.
+// A running job is finished before runDaemon returns.
+// Missed jobs run once after a restart, jobs that never ran wait for their first scheduled time.
+func runDaemon(opts *options, w io.Writer, stop <-chan os.Signal) error {
+	lock, err := dblist.TryLockFile(opts.state + ".lock")
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	cw, err := dblist.WatchConfig(opts.config, opts.overrides())
+	if err != nil {
+		return err
+	}
+	defer cw.Close()
+
+	d := &daemon{opts: opts, cfg: cw.Config(), w: w, log: log.New(w, "", log.LstdFlags)}
+	if err := d.readState(); err != nil {
+		return err
+	}
+	d.started = d.now()
+	d.log.Printf("daemon started, config %s", opts.config)
+
+	timer := time.NewTimer(0)
+	<-timer.C
+	for {
+		wake := time.Time{}
+		for _, job := range daemonJobs {
+			sch, err := dblist.ParseSchedule(job.schedule(d.cfg.Daemon))
+			if err != nil {
+				continue // no schedule, schedules are validated by config reader
+			}
+			next := sch.Next(d.lastRun(job.name))
+			if !next.After(d.now()) {
+				select {
+				case <-stop:
+					return d.shutdown()
+				default:
+				}
+				d.runJob(job)
+				next = sch.Next(d.lastRun(job.name))
+			}
+			if wake.IsZero() || next.Before(wake) {
+				wake = next
+			}
+		}
+		if err := d.writeState(); err != nil {
+			d.log.Printf("%v", err)
+		}
+
+		if !wake.IsZero() {
+			timer.Reset(wake.Sub(d.now()))
+		}
+		select {
+		case <-stop:
+			timer.Stop()
+			return d.shutdown()
+		case <-cw.Updates():
+			d.cfg = cw.Config()
+			opts.cached = nil
+			d.log.Printf("config %s reloaded", opts.config)
+		case err := <-cw.Errors():
+			d.log.Printf("config %s: %v", opts.config, err)
+		case <-timer.C:
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+	}
+}
+
+// now returns current time in the form of times in file names, schedules are in this form.
+func (d *daemon) now() time.Time {
+	return d.cfg.WallClock(time.Now())
+}
+
+func (d *daemon) lastRun(name string) time.Time {
+	if js, ok := d.state.Jobs[name]; ok && !js.LastRun.IsZero() {
+		return js.LastRun
+	}
+	return d.started
+}
+
+func (d *daemon) runJob(job daemonJob) {
+	start := time.Now()
+	js := &jobState{LastRun: d.now()}
+	d.state.Jobs[job.name] = js
+	d.log.Printf("%s started", job.name)
+	if err := job.run(d); err != nil {
+		js.Error = err.Error()
+		d.log.Printf("%s failed: %v", job.name, err)
+		return
+	}
+	d.log.Printf("%s done in %s", job.name, time.Since(start).Round(time.Millisecond))
+}
+
+func (d *daemon) shutdown() error {
+	d.log.Printf("daemon stopped")
+	return d.writeState()
+}
+
+func (d *daemon) readState() error {
+	d.state = daemonState{Jobs: make(map[string]*jobState)}
+	b, err := ioutil.ReadFile(d.opts.state)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, &d.state); err != nil {
+		return fmt.Errorf("bad state file %s: %w", d.opts.state, err)
+	}
+	if d.state.Jobs == nil {
+		d.state.Jobs = make(map[string]*jobState)
+	}
+	return nil
+}
+
+// writeState replaces the state file, so it is never partially written.
+func (d *daemon) writeState() error {
+	b, err := json.MarshalIndent(d.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(d.opts.state, b)
+}
+
+func writeFileAtomic(filename string, b []byte) error {
+	tmp := filename + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func (d *daemon) scan() error {
+	d.opts.cached = nil
+	s, err := readScan(d.opts)
+	if err != nil {
+		return err
+	}
+	d.opts.cached = s
+	return nil
+}
+
+// scanned makes sure there is the latest scan.
+func (d *daemon) scanned() error {
+	if d.opts.cached != nil {
+		return nil
+	}
+	return d.scan()
+}
+
+func (d *daemon) verify() error {
+	if err := d.scanned(); err != nil {
+		return err
+	}
+	return cmdVerify(d.opts, nil, d.w)
+}
+
+func (d *daemon) report() error {
+	if err := d.scanned(); err != nil {
+		return err
+	}
+	filename := d.cfg.Daemon.ReportFile
+	if filename == "" {
+		return cmdReport(d.opts, nil, d.w)
+	}
+	opts := *d.opts
+	opts.json = false
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		opts.format = "json"
+	case ".csv":
+		opts.format = "csv"
+	default:
+		opts.format = "markdown"
+	}
+	var b strings.Builder
+	if err := cmdReport(&opts, nil, &b); err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, []byte(b.String()))
+}
+
+// sync reads config paths again, so files deleted after the latest scan are not copied.
+func (d *daemon) sync() error {
+	d.opts.cached = nil
+	opts := *d.opts
+	opts.dryRun = false
+	return cmdSync(&opts, nil, d.w)
+}
+
+// upload reads config paths again, so it sees upload marks set since the latest scan.
+func (d *daemon) upload() error {
+	d.opts.cached = nil
+	opts := *d.opts
+	opts.dryRun = false
+	err := cmdUpload(&opts, nil, d.w)
+	d.opts.cached = nil // marked files
+	return err
+}
+
+// prune reads config paths again, so files created after the latest scan are kept as the newest.
+func (d *daemon) prune() error {
+	d.opts.cached = nil
+	opts := *d.opts
+	opts.dryRun = false
+	err := cmdPrune(&opts, nil, d.w)
+	d.opts.cached = nil // deleted files
+	return err
+}
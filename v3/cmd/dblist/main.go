@@ -0,0 +1,881 @@
+// Command dblist lists, selects and deletes database backup files according to a config json file.
+// Usage:
+//
+//	dblist <command> [flags] [files]
+//
+// Example:
+//
+//	dblist plan -config ./dblist.json -keep 2 -json
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/zavla/dblist/v3"
+	"github.com/zavla/dblist/v3/catalog"
+	"github.com/zavla/dblist/v3/httpapi"
+	"github.com/zavla/dblist/v3/remote"
+)
+
+// options holds flags common to all commands.
+type options struct {
+	config      string
+	json        bool
+	keep        uint
+	maxPercent  int
+	dryRun      bool
+	format      string
+	listen      string
+	textfile    string
+	period      time.Duration
+	grace       time.Duration
+	notify      bool
+	root        string
+	vars        varsFlag
+	listing     string
+	catalog     string
+	at          string
+	state       string
+	allowPrune  bool
+	lockTimeout time.Duration
+	cached      *scan // the latest scan of daemon jobs, commands read config paths if nil
+}
+
+// overrides returns config overrides from -root and -var flags.
+func (opts *options) overrides() dblist.ConfigOverrides {
+	return dblist.ConfigOverrides{Root: opts.root, Vars: opts.vars}
+}
+
+// varsFlag collects repeated -var NAME=value flags.
+type varsFlag map[string]string
+
+func (v varsFlag) String() string {
+	pairs := make([]string, 0, len(v))
+	for name, value := range v {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
+
+func (v varsFlag) Set(s string) error {
+	i := strings.IndexByte(s, '=')
+	if i <= 0 {
+		return fmt.Errorf("%q is not NAME=value", s)
+	}
+	v[s[:i]] = s[i+1:]
+	return nil
+}
+
+type command struct {
+	name  string
+	usage string
+	run   func(opts *options, args []string, w io.Writer) error
+}
+
+var commands = []command{
+	{"list", "prints all backup files in config paths", cmdList},
+	{"latest", "prints the newest files of every group", cmdLatest},
+	{"uncovered", "prints files not covered by config", cmdUncovered},
+	{"plan", "prints files that prune would delete", cmdPlan},
+	{"prune", "deletes outdated files", cmdPrune},
+	{"sync", "copies the newest files to mirrors from \"mirrors\" section of config", cmdSync},
+	{"upload", "uploads files not uploaded yet as \"upload\" section of config says and marks them uploaded", cmdUpload},
+	{"verify", "checks that config lines have files and deletion plans are safe", cmdVerify},
+	{"missed", "checks config lines schedules and prints missed backups", cmdMissed},
+	{"report", "prints a summary of every database", cmdReport},
+	{"dashboard", "prints html page with status of backups", cmdDashboard},
+	{"metrics", "prints prometheus metrics, writes a textfile or serves /metrics", cmdMetrics},
+	{"notify", "sends notifications about stale backups and uncovered files", cmdNotify},
+	{"mark-uploaded", "marks files given as arguments as uploaded", cmdMarkUploaded},
+	{"lint", "checks config against files on disk or a -listing snapshot", cmdLint},
+	{"history", "prints files recorded in -catalog, for databases given as arguments", cmdHistory},
+	{"watch", "prints changes of backup files in config paths until interrupted", cmdWatch},
+	{"serve", "serves json api of backup inventory and /metrics on -listen address", cmdServe},
+	{"daemon", "runs jobs on schedules from \"daemon\" section of config until interrupted", cmdDaemon},
+	{"discover", "prints a config json for backup files in directories given as arguments", cmdDiscover},
+}
+
+// errProblemsFound makes dblist exit with non zero code after it has printed problems.
+var errProblemsFound = errors.New("problems found")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == args[0] {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(stderr, "dblist: unknown command %q\n", args[0])
+		usage(stderr)
+		return 2
+	}
+
+	opts := options{vars: varsFlag{}}
+	fs := flag.NewFlagSet("dblist "+cmd.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opts.config, "config", "dblist.json", "config json `file`")
+	fs.BoolVar(&opts.json, "json", false, "print output as json")
+	fs.UintVar(&opts.keep, "keep", 1, "number of newest `copies` to keep in every group")
+	fs.IntVar(&opts.maxPercent, "max-percent", dblist.DefaultMaxDeletePercent, "maximum `percent` of files in a directory to delete")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "prune, sync and upload print files instead of changing them")
+	fs.StringVar(&opts.format, "format", "markdown", "report `format`: json, csv or markdown")
+	fs.StringVar(&opts.listen, "listen", "", "metrics and serve listen on this `address`, ex. :9101")
+	fs.StringVar(&opts.textfile, "textfile", "", "metrics writes node exporter textfile collector `file`")
+	fs.DurationVar(&opts.period, "period", 7*24*time.Hour, "missed checks schedules for this `duration` back from now")
+	fs.DurationVar(&opts.grace, "grace", 0, "missed expects a backup during this `duration` after scheduled time, default is till the next scheduled time")
+	fs.BoolVar(&opts.notify, "notify", false, "prune, verify and missed send notifications to notifiers from config")
+	fs.StringVar(&opts.root, "root", "", "root `directory` of relative config paths, default is $DBLIST_ROOT")
+	fs.StringVar(&opts.listing, "listing", "", "read files from a directory listing `file` instead of config paths, see 'lint'")
+	fs.StringVar(&opts.catalog, "catalog", "", "scan incrementally and record files in a catalog `file`, history reads it")
+	fs.StringVar(&opts.at, "at", "", "history prints files that existed at this `time`: 2006-01-02 or 2006-01-02T15:04")
+	fs.StringVar(&opts.state, "state", "dblist-state.json", "daemon keeps times of jobs in this `file` and locks file.lock")
+	fs.BoolVar(&opts.allowPrune, "allow-prune", false, "serve deletes files on POST /prune?dry_run=false")
+	fs.DurationVar(&opts.lockTimeout, "lock-timeout", dblist.DefaultLockTimeout, "prune, sync, upload and mark-uploaded wait this `duration` for other tools to release a directory")
+	fs.Var(opts.vars, "var", "`NAME=value` for ${NAME} in config paths, may be repeated")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	if err := cmd.run(&opts, fs.Args(), stdout); err != nil {
+		if err != errProblemsFound {
+			fmt.Fprintf(stderr, "dblist %s: %v\n", cmd.name, err)
+		}
+		return 1
+	}
+	return 0
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: dblist <command> [flags] [files]\ncommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-14s %s\n", c.name, c.usage)
+	}
+	fmt.Fprintf(w, "run 'dblist <command> -h' for flags\n")
+}
+
+// scan holds config and files read from config paths.
+type scan struct {
+	cfg            *dblist.Config
+	conf           []dblist.ConfigLine
+	nameTosuffixes map[string][]string
+	filesByPath    map[string][]dblist.FileInfoWin
+	paths          []string // sorted keys of filesByPath
+}
+
+func readScan(opts *options) (*scan, error) {
+	if opts.cached != nil {
+		return opts.cached, nil
+	}
+	cfg, err := dblist.ReadConfigFileWith(opts.config, opts.overrides())
+	if err != nil {
+		return nil, err
+	}
+	conf := cfg.Databases
+	dblist.SortConfig(conf)
+	s := &scan{
+		cfg:            cfg,
+		conf:           conf,
+		nameTosuffixes: dblist.GetMapFilenameToSuffixes(conf),
+	}
+	if opts.listing != "" {
+		if s.filesByPath, err = dblist.ReadListingFile(opts.listing); err != nil {
+			return nil, err
+		}
+	} else if opts.catalog != "" {
+		if s.filesByPath, err = scanCatalog(opts.catalog, s); err != nil {
+			return nil, err
+		}
+	} else {
+		s.filesByPath = dblist.ReadFilesFromPaths(dblist.GetUniquePaths(conf))
+	}
+	for path := range s.filesByPath {
+		s.paths = append(s.paths, path)
+	}
+	sort.Strings(s.paths)
+	return s, nil
+}
+
+// now returns current time in the form of times in file names.
+func (s *scan) now() time.Time {
+	return s.cfg.WallClock(time.Now())
+}
+
+// fileEntry is a json representation of a file.
+type fileEntry struct {
+	Path     string    `json:"path"`
+	Name     string    `json:"name"`
+	DBName   string    `json:"dbname"`
+	Suffix   string    `json:"suffix"`
+	Size     int64     `json:"size"`
+	Modtime  time.Time `json:"modtime"`
+	Uploaded bool      `json:"uploaded"`
+}
+
+func (s *scan) entries(path string, files []dblist.FileInfoWin) []fileEntry {
+	ret := make([]fileEntry, 0, len(files))
+	for _, f := range files {
+		dbname, suffix := dblist.GroupFunc(f.Name(), s.nameTosuffixes)
+		ret = append(ret, fileEntry{
+			Path:     path,
+			Name:     f.Name(),
+			DBName:   dbname,
+			Suffix:   suffix,
+			Size:     f.Size(),
+			Modtime:  f.ModTime(),
+			Uploaded: f.IsUploaded(),
+		})
+	}
+	return ret
+}
+
+// collect applies selector to files of every path.
+func (s *scan) collect(selector func(files []dblist.FileInfoWin) []dblist.FileInfoWin) []fileEntry {
+	ret := []fileEntry{}
+	for _, path := range s.paths {
+		files := make([]dblist.FileInfoWin, len(s.filesByPath[path]))
+		copy(files, s.filesByPath[path])
+		ret = append(ret, s.entries(path, selector(files))...)
+	}
+	return ret
+}
+
+func printEntries(w io.Writer, entries []fileEntry, asJSON bool) error {
+	if asJSON {
+		return printJSON(w, entries)
+	}
+	for _, e := range entries {
+		if _, err := fmt.Fprintln(w, filepath.Join(e.Path, e.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func cmdList(opts *options, args []string, w io.Writer) error {
+	s, err := readScan(opts)
+	if err != nil {
+		return err
+	}
+	return printEntries(w, s.collect(func(files []dblist.FileInfoWin) []dblist.FileInfoWin {
+		return files
+	}), opts.json)
+}
+
+func cmdLatest(opts *options, args []string, w io.Writer) error {
+	s, err := readScan(opts)
+	if err != nil {
+		return err
+	}
+	return printEntries(w, s.collect(func(files []dblist.FileInfoWin) []dblist.FileInfoWin {
+		return dblist.GetLastFilesGroupedByFunc(files, dblist.GroupFunc, s.nameTosuffixes, opts.keep)
+	}), opts.json)
+}
+
+func cmdUncovered(opts *options, args []string, w io.Writer) error {
+	s, err := readScan(opts)
+	if err != nil {
+		return err
+	}
+	return printEntries(w, s.collect(func(files []dblist.FileInfoWin) []dblist.FileInfoWin {
+		return dblist.GetFilesNotCoveredByConfigFile(files, s.conf, dblist.GroupFunc, s.nameTosuffixes)
+	}), opts.json)
+}
+
+func cmdPlan(opts *options, args []string, w io.Writer) error {
+	opts.dryRun = true
+	return cmdPrune(opts, args, w)
+}
+
+func cmdPrune(opts *options, args []string, w io.Writer) error {
+	if opts.listing != "" && !opts.dryRun {
+		return errors.New("prune can't delete files of a listing, use -dry-run")
+	}
+	s, err := readScan(opts)
+	if err != nil {
+		return err
+	}
+	res, err := dblist.Prune(s.filesByPath, s.nameTosuffixes, dblist.PruneOptions{Keep: opts.keep, MaxPercent: opts.maxPercent,
+		DryRun: opts.dryRun, Lock: dblist.LockOptions{Timeout: opts.lockTimeout, Op: "dblist prune"}})
+	if err != nil {
+		if !opts.dryRun {
+			err = notify(opts, err, dblist.Event{Kind: dblist.EventVerifyFailed, Time: s.now(),
+				Message: "prune aborted: " + err.Error()})
+		}
+		return err
+	}
+	entries := make([]fileEntry, 0, len(res.Files))
+	for _, f := range res.Files {
+		entries = append(entries, s.entries(f.Path, []dblist.FileInfoWin{f.FileInfoWin})...)
+	}
+	if err := printEntries(w, entries, opts.json); err != nil || opts.dryRun {
+		return err
+	}
+	if len(res.Failed) != 0 {
+		err = fmt.Errorf("some files were not deleted: %s", strings.Join(res.Failed, "; "))
+	}
+	return notify(opts, err, prunedEvents(entries, s.now())...)
+}
+
+// withPathLock calls f holding an exclusive lock of a directory, see dblist.LockPath.
+func withPathLock(opts *options, path, op string, f func()) error {
+	lock, err := dblist.LockPath(path, dblist.LockOptions{Timeout: opts.lockTimeout, Op: "dblist " + op})
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	f()
+	return nil
+}
+
+// syncEntry is a file copied to or removed from a mirror.
+type syncEntry struct {
+	Op   string `json:"op"` // copy or remove
+	From string `json:"from,omitempty"`
+	To   string `json:"to"`
+}
+
+// cmdSync copies the newest files of every group to every mirror, see remote.Sync.
+// Files of every path go to its own directory of a mirror, see remote.SourceDirs.
+// With -dry-run it prints what would be copied and removed.
+func cmdSync(opts *options, args []string, w io.Writer) error {
+	if opts.listing != "" && !opts.dryRun {
+		return errors.New("sync can't copy files of a listing, use -dry-run")
+	}
+	s, err := readScan(opts)
+	if err != nil {
+		return err
+	}
+	if len(s.cfg.Mirrors) == 0 {
+		return errors.New("no mirrors in config")
+	}
+	entries := []syncEntry{}
+	failed := []string{}
+	dirs := remote.SourceDirs(s.conf, s.paths)
+	for _, m := range s.cfg.Mirrors {
+		for _, path := range s.paths {
+			to := filepath.Join(m.To, dirs[path])
+			t := remote.Target{Storage: &remote.DirStorage{Dir: to, LockTimeout: opts.lockTimeout},
+				Keep: m.Keep, Prune: m.Prune, Verify: m.Verify, LockTimeout: opts.lockTimeout}
+			var copied []remote.Source
+			var removed []string
+			if opts.dryRun {
+				stored, err := t.Storage.List()
+				if err != nil {
+					failed = append(failed, fmt.Sprintf("%s: %v", to, err))
+					continue
+				}
+				plan := remote.PlanSync(path, s.filesByPath[path], stored, s.nameTosuffixes, t)
+				copied, removed = plan.Copy, plan.Remove
+			} else {
+				res, err := remote.Sync(path, s.filesByPath[path], s.nameTosuffixes, t)
+				if err != nil {
+					failed = append(failed, err.Error())
+				}
+				if res == nil {
+					continue
+				}
+				copied, removed = res.Copied, res.Removed
+			}
+			for _, src := range copied {
+				entries = append(entries, syncEntry{Op: "copy", From: src.Filename(), To: filepath.Join(to, src.Name())})
+			}
+			for _, name := range removed {
+				entries = append(entries, syncEntry{Op: "remove", To: filepath.Join(to, name)})
+			}
+		}
+	}
+	if opts.json {
+		err = printJSON(w, entries)
+	} else {
+		for _, e := range entries {
+			line := e.Op + " " + e.To
+			if e.From != "" {
+				line = e.Op + " " + e.From + " " + e.To
+			}
+			if _, err = fmt.Fprintln(w, line); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if len(failed) != 0 {
+		return fmt.Errorf("sync failed: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// cmdUpload uploads backup files that are not uploaded yet, see remote.Uploader.
+// Files of every path go to its own directory of the upload directory, see remote.SourceDirs.
+// SIGTERM or Ctrl-C stops it, interrupted uploads resume on the next run.
+// With -dry-run it prints the queue.
+func cmdUpload(opts *options, args []string, w io.Writer) error {
+	if opts.listing != "" && !opts.dryRun {
+		return errors.New("upload can't read files of a listing, use -dry-run")
+	}
+	s, err := readScan(opts)
+	if err != nil {
+		return err
+	}
+	c := s.cfg.Upload
+	if c.To == "" {
+		return errors.New("no upload section in config")
+	}
+	queue := remote.Queue(s.filesByPath, s.nameTosuffixes)
+	var uploaded []remote.Source
+	var errupload error
+	if opts.dryRun {
+		uploaded = queue
+	} else {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(interrupt)
+		go func() {
+			select {
+			case <-interrupt:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		dirs := remote.SourceDirs(s.conf, s.paths)
+		u := &remote.Uploader{
+			Storage: func(path string) remote.ResumableStorage {
+				return &remote.DirStorage{Dir: filepath.Join(c.To, dirs[path]), LockTimeout: opts.lockTimeout}
+			},
+			Concurrency:    c.Concurrency,
+			BytesPerSecond: c.BytesPerSecond,
+			ChunkSize:      c.ChunkSize,
+			Retries:        c.Retries,
+			LockTimeout:    opts.lockTimeout,
+		}
+		res, err := u.Upload(ctx, queue)
+		if res != nil {
+			uploaded = res.Uploaded
+		}
+		errupload = err
+	}
+	entries := []fileEntry{}
+	for _, src := range uploaded {
+		entries = append(entries, s.entries(src.Path, []dblist.FileInfoWin{src.FileInfoWin})...)
+	}
+	if err := printEntries(w, entries, opts.json); err != nil {
+		return err
+	}
+	return errupload
+}
+
+// prunedEvents returns an event with deleted files for every path.
+func prunedEvents(deleted []fileEntry, now time.Time) []dblist.Event {
+	ret := []dblist.Event{}
+	for _, e := range deleted {
+		if len(ret) == 0 || ret[len(ret)-1].Path != e.Path {
+			ret = append(ret, dblist.Event{Kind: dblist.EventPruned, Time: now, Path: e.Path})
+		}
+		last := &ret[len(ret)-1]
+		last.Files = append(last.Files, e.Name)
+		last.Message = fmt.Sprintf("%d files deleted in %s", len(last.Files), e.Path)
+	}
+	return ret
+}
+
+// notify sends events to notifiers from config file if -notify is set.
+// It returns err, or a notification error if err is nil or errProblemsFound.
+func notify(opts *options, err error, events ...dblist.Event) error {
+	if !opts.notify || len(events) == 0 {
+		return err
+	}
+	if errnotify := sendEvents(opts, events); errnotify != nil && (err == nil || err == errProblemsFound) {
+		err = errnotify
+	}
+	return err
+}
+
+func sendEvents(opts *options, events []dblist.Event) error {
+	cfg, err := dblist.ReadConfigFileWith(opts.config, opts.overrides())
+	if err != nil {
+		return err
+	}
+	n, err := dblist.NewNotifiers(cfg.Notify)
+	if err != nil {
+		return err
+	}
+	return n.Notify(events)
+}
+
+// problem is a json representation of a verification failure.
+type problem struct {
+	Path    string `json:"path"`
+	Problem string `json:"problem"`
+}
+
+func cmdVerify(opts *options, args []string, w io.Writer) error {
+	s, err := readScan(opts)
+	if err != nil {
+		return err
+	}
+	problems := []problem{}
+	for _, line := range s.conf {
+		paths := dblist.PathsOfConfigLine(line, s.filesByPath)
+		if len(paths) == 0 {
+			problems = append(problems, problem{line.Path, "no directories match"})
+			continue
+		}
+		if _, ok := s.filesByPath[paths[0]]; !ok {
+			problems = append(problems, problem{line.Path, "directory can't be read"})
+			continue
+		}
+		if len(dblist.FilesOfConfigLine(line, s.filesByPath, s.nameTosuffixes)) == 0 {
+			problems = append(problems, problem{line.Path, fmt.Sprintf("no files for %s%s", line.Filename, line.Suffix)})
+		}
+	}
+	for _, path := range s.paths {
+		files := s.filesByPath[path]
+		todelete := dblist.GetFilesToDelete(files, dblist.GroupFunc, s.nameTosuffixes, opts.keep)
+		err := dblist.CheckDeletionPlan(files, todelete, dblist.GroupFunc, s.nameTosuffixes, opts.maxPercent)
+		if err != nil {
+			problems = append(problems, problem{path, err.Error()})
+		}
+	}
+
+	if opts.json {
+		err = printJSON(w, problems)
+	} else {
+		for _, p := range problems {
+			if _, err = fmt.Fprintf(w, "%s: %s\n", p.Path, p.Problem); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if len(problems) != 0 {
+		now := s.now()
+		events := make([]dblist.Event, 0, len(problems))
+		for _, p := range problems {
+			events = append(events, dblist.Event{Kind: dblist.EventVerifyFailed, Time: now, Path: p.Path, Message: p.Problem})
+		}
+		return notify(opts, errProblemsFound, events...)
+	}
+	return nil
+}
+
+func cmdMissed(opts *options, args []string, w io.Writer) error {
+	s, err := readScan(opts)
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	missed, err := dblist.CheckSchedules(s.conf, s.filesByPath, now.Add(-opts.period), now, opts.grace)
+	if err != nil {
+		return err
+	}
+	if opts.json {
+		err = printJSON(w, missed)
+	} else {
+		for _, m := range missed {
+			if _, err = fmt.Fprintln(w, m); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if len(missed) != 0 {
+		return notify(opts, errProblemsFound, dblist.MissedEvents(missed, now)...)
+	}
+	return nil
+}
+
+func cmdReport(opts *options, args []string, w io.Writer) error {
+	s, err := readScan(opts)
+	if err != nil {
+		return err
+	}
+	rep := dblist.BuildReport(s.conf, s.filesByPath, opts.keep)
+	if opts.json {
+		opts.format = "json"
+	}
+	switch opts.format {
+	case "json":
+		return rep.WriteJSON(w)
+	case "csv":
+		return rep.WriteCSV(w)
+	case "markdown", "md":
+		return rep.WriteMarkdown(w)
+	}
+	return fmt.Errorf("unknown report format %q", opts.format)
+}
+
+func cmdDashboard(opts *options, args []string, w io.Writer) error {
+	s, err := readScan(opts)
+	if err != nil {
+		return err
+	}
+	return dblist.WriteDashboard(w, s.conf, s.filesByPath, s.now())
+}
+
+func cmdMetrics(opts *options, args []string, w io.Writer) error {
+	if opts.listen != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", dblist.MetricsHandler(opts.config, opts.overrides()))
+		return http.ListenAndServe(opts.listen, mux)
+	}
+	s, err := readScan(opts)
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	if opts.textfile != "" {
+		return dblist.WriteMetricsFile(opts.textfile, s.conf, s.filesByPath, now)
+	}
+	return dblist.WriteMetrics(w, s.conf, s.filesByPath, now)
+}
+
+func cmdServe(opts *options, args []string, w io.Writer) error {
+	if opts.listen == "" {
+		return errors.New("no -listen address given")
+	}
+	api := httpapi.New(opts.config, opts.overrides())
+	api.Keep, api.MaxPercent, api.AllowPrune = opts.keep, opts.maxPercent, opts.allowPrune
+	mux := http.NewServeMux()
+	mux.Handle("/", api)
+	mux.Handle("/metrics", dblist.MetricsHandler(opts.config, opts.overrides()))
+	return http.ListenAndServe(opts.listen, mux)
+}
+
+func cmdNotify(opts *options, args []string, w io.Writer) error {
+	s, err := readScan(opts)
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	events := append(dblist.StaleEvents(s.conf, s.filesByPath, now), dblist.UncoveredEvents(s.conf, s.filesByPath, now)...)
+	if opts.json {
+		err = printJSON(w, events)
+	} else {
+		for _, e := range events {
+			if _, err = fmt.Fprintln(w, e); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil || len(events) == 0 {
+		return err
+	}
+	return sendEvents(opts, events)
+}
+
+func cmdMarkUploaded(opts *options, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("no files given")
+	}
+	marked := []string{}
+	failed := []string{}
+	for _, name := range args {
+		err := withPathLock(opts, filepath.Dir(name), "mark-uploaded", func() {
+			if err := dblist.MarkUploaded(name); err != nil {
+				failed = append(failed, err.Error())
+				return
+			}
+			marked = append(marked, name)
+		})
+		if err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	var err error
+	if opts.json {
+		err = printJSON(w, marked)
+	} else {
+		for _, name := range marked {
+			if _, err = fmt.Fprintln(w, name); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if len(failed) != 0 {
+		return fmt.Errorf("some files were not marked: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+func cmdWatch(opts *options, args []string, w io.Writer) error {
+	c, err := dblist.ReadConfigFileWith(opts.config, opts.overrides())
+	if err != nil {
+		return err
+	}
+	bw, err := dblist.WatchBackups(c.Databases)
+	if err != nil {
+		return err
+	}
+	defer bw.Close()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case ev := <-bw.Events():
+			if opts.json {
+				err = enc.Encode(ev) // a json object on every line
+			} else {
+				_, err = fmt.Fprintf(w, "%s %s\n", ev.Kind, filepath.Join(ev.Path, ev.Name))
+			}
+			if err != nil {
+				return err
+			}
+		case err := <-bw.Errors():
+			if err == nil {
+				err = dblist.ErrWatcherStopped
+			}
+			if err != dblist.ErrEventsOverflow {
+				return err
+			}
+			fmt.Fprintln(os.Stderr, "dblist watch: some changes were lost, read directories again")
+		case <-interrupt:
+			return nil
+		}
+	}
+}
+
+func cmdDiscover(opts *options, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("no directories given")
+	}
+	conf := []dblist.ConfigLine{}
+	for _, dir := range args {
+		lines, err := dblist.Discover(dir)
+		if err != nil {
+			return err
+		}
+		conf = append(conf, lines...)
+	}
+	return dblist.WriteConfigJSON(w, conf)
+}
+
+func cmdLint(opts *options, args []string, w io.Writer) error {
+	s, err := readScan(opts)
+	if err != nil {
+		return err
+	}
+	issues := dblist.LintConfig(s.conf, s.filesByPath)
+	if opts.json {
+		err = printJSON(w, issues)
+	} else {
+		for _, issue := range issues {
+			if _, err = fmt.Fprintln(w, issue); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if len(issues) != 0 {
+		return errProblemsFound
+	}
+	return nil
+}
+
+// scanCatalog reads files of config paths incrementally and records them in a catalog file.
+func scanCatalog(filename string, s *scan) (map[string][]dblist.FileInfoWin, error) {
+	c, err := catalog.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	c.Checksum = catalog.FileSHA256
+	c.Location = s.cfg.Location()
+	return c.ReadFilesFromPaths(dblist.GetUniquePaths(s.conf), s.nameTosuffixes, catalog.ScanOptions{})
+}
+
+func cmdHistory(opts *options, args []string, w io.Writer) error {
+	if opts.catalog == "" {
+		return errors.New("no -catalog given")
+	}
+	q := catalog.Query{}
+	if opts.at != "" {
+		from, err := time.ParseInLocation("2006-01-02T15:04", opts.at, time.Local)
+		to := from
+		if err != nil {
+			from, err = time.ParseInLocation("2006-01-02", opts.at, time.Local)
+			to = from.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		if err != nil {
+			return fmt.Errorf("bad -at time %q", opts.at)
+		}
+		q.From, q.To = from, to
+	}
+	c, err := catalog.Open(opts.catalog)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	dbnames := args
+	if len(dbnames) == 0 {
+		dbnames = []string{""} // all databases
+	}
+	records := []catalog.Record{}
+	for _, dbname := range dbnames {
+		q.DBName = dbname
+		found, err := c.Find(q)
+		if err != nil {
+			return err
+		}
+		records = append(records, found...)
+	}
+	if opts.json {
+		return printJSON(w, records)
+	}
+	for _, r := range records {
+		deleted := ""
+		if !r.DeletedAt.IsZero() {
+			deleted = "deleted " + r.DeletedAt.Local().Format("2006-01-02 15:04")
+		}
+		_, err := fmt.Fprintf(w, "%s\t%d\tseen %s\t%s\n", filepath.Join(r.Path, r.Name), r.Size,
+			r.FirstSeen.Local().Format("2006-01-02 15:04"), deleted)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zavla/dblist/v3"
+)
+
+// testConfig creates a directory with backup files and a config json file for it.
+func testConfig(t *testing.T, names ...string) (dir, configfile string) {
+	dir, err := ioutil.TempDir("", "dblist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conf := []dblist.ConfigLine{
+		{Path: dir, Filename: "db", Suffix: "-FULL.bak", Days: 1},
+		{Path: dir, Filename: "db", Suffix: "-differ.bak", Days: 1},
+	}
+	b, err := json.Marshal(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configfile = filepath.Join(dir, "config.json")
+	if err := ioutil.WriteFile(configfile, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir, configfile
+}
+
+var testNames = []string{
+	"db_2021-08-01T21-00-00-001-FULL.bak",
+	"db_2021-08-08T21-00-00-001-FULL.bak",
+	"db_2021-08-09T21-00-00-001-differ.bak",
+	"db_2021-08-10T21-00-00-001-differ.bak",
+	"other_2021-08-10T21-00-00-001-FULL.bak",
+}
+
+func TestRun(t *testing.T) {
+	dir, configfile := testConfig(t, testNames...)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		want     []string
+	}{
+		{"no command", []string{}, 2, nil},
+		{"unknown command", []string{"unknown"}, 2, nil},
+		{"latest", []string{"latest", "-config", configfile}, 0, []string{
+			"db_2021-08-10T21-00-00-001-differ.bak",
+			"db_2021-08-08T21-00-00-001-FULL.bak",
+		}},
+		{"uncovered", []string{"uncovered", "-config", configfile}, 0, []string{
+			"other_2021-08-10T21-00-00-001-FULL.bak",
+		}},
+		{"plan", []string{"plan", "-config", configfile}, 0, []string{
+			"db_2021-08-01T21-00-00-001-FULL.bak",
+			"db_2021-08-09T21-00-00-001-differ.bak",
+		}},
+		{"plan over limit", []string{"plan", "-config", configfile, "-max-percent", "10"}, 1, nil},
+		{"verify", []string{"verify", "-config", configfile}, 0, nil},
+		{"report", []string{"report", "-config", configfile, "-format", "csv"}, 0, nil},
+		{"dashboard", []string{"dashboard", "-config", configfile}, 0, nil},
+		{"metrics", []string{"metrics", "-config", configfile}, 0, nil},
+		{"missed", []string{"missed", "-config", configfile}, 0, nil},
+		{"discover", []string{"discover", dir}, 0, nil},
+		{"discover without directories", []string{"discover"}, 1, nil},
+		{"report unknown format", []string{"report", "-config", configfile, "-format", "xls"}, 1, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			code := run(tt.args, stdout, stderr)
+			if code != tt.wantCode {
+				t.Fatalf("run(%v) = %d, want %d, stderr: %s", tt.args, code, tt.wantCode, stderr)
+			}
+			if tt.want == nil {
+				return
+			}
+			want := ""
+			for _, name := range tt.want {
+				want += filepath.Join(dir, name) + "\n"
+			}
+			if stdout.String() != want {
+				t.Errorf("run(%v) printed\n%s\nwant\n%s", tt.args, stdout, want)
+			}
+		})
+	}
+}
+
+func TestRunPrune(t *testing.T) {
+	dir, configfile := testConfig(t, testNames...)
+	defer os.RemoveAll(dir)
+
+	// another tool holds the directory
+	lock, err := dblist.LockPath(dir, dblist.LockOptions{Shared: true, Op: "upload"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if code := run([]string{"prune", "-config", configfile, "-lock-timeout", "100ms"}, stdout, stderr); code != 1 || stdout.Len() != 0 {
+		t.Errorf("prune of a locked directory = %d, want 1 without deleted files\n%s%s", code, stdout, stderr)
+	}
+	lock.Unlock()
+
+	stderr.Reset()
+	if code := run([]string{"prune", "-config", configfile, "-json"}, stdout, stderr); code != 0 {
+		t.Fatalf("prune failed with code %d: %s", code, stderr)
+	}
+	deleted := []fileEntry{}
+	if err := json.Unmarshal(stdout.Bytes(), &deleted); err != nil {
+		t.Fatalf("prune printed bad json: %v", err)
+	}
+	if len(deleted) != 2 {
+		t.Errorf("prune deleted %v, want 2 files", deleted)
+	}
+	for _, e := range deleted {
+		if _, err := os.Stat(filepath.Join(dir, e.Name)); !os.IsNotExist(err) {
+			t.Errorf("file %s still exists", e.Name)
+		}
+	}
+
+	stdout.Reset()
+	if code := run([]string{"list", "-config", configfile}, stdout, stderr); code != 0 {
+		t.Fatalf("list failed with code %d: %s", code, stderr)
+	}
+	if n := strings.Count(stdout.String(), "\n"); n != 3 {
+		t.Errorf("list printed %d files after prune, want 3", n)
+	}
+}
+
+func TestPruneOutdatedScan(t *testing.T) {
+	dir, configfile := testConfig(t, testNames...)
+	defer os.RemoveAll(dir)
+	opts := &options{config: configfile, keep: 1, maxPercent: 100}
+	s, err := readScan(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.cached = s // like the daemon, prune uses a scan made earlier
+
+	// the newer FULL backup disappears after the scan, the older one is the last FULL backup now
+	if err := os.Remove(filepath.Join(dir, testNames[1])); err != nil {
+		t.Fatal(err)
+	}
+	stdout := &bytes.Buffer{}
+	if err := cmdPrune(opts, nil, stdout); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), filepath.Join(dir, testNames[2])+"\n"; got != want {
+		t.Errorf("prune deleted %q, want %q", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, testNames[0])); err != nil {
+		t.Errorf("prune deleted the last FULL backup: %v", err)
+	}
+}
+
+func TestRunSync(t *testing.T) {
+	dir, _ := testConfig(t, testNames...)
+	defer os.RemoveAll(dir)
+	mirror := filepath.Join(dir, "mirror")
+	b, _ := json.Marshal(map[string]interface{}{
+		"version":   2,
+		"databases": []dblist.ConfigLine{{Path: dir, Filename: "db", Suffix: "-FULL.bak"}},
+		"mirrors":   []dblist.MirrorConfig{{To: mirror}},
+	})
+	configfile := filepath.Join(dir, "sync.json")
+	if err := ioutil.WriteFile(configfile, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if code := run([]string{"sync", "-config", configfile, "-dry-run"}, stdout, stderr); code != 0 {
+		t.Fatalf("sync -dry-run failed with code %d: %s", code, stderr)
+	}
+	want := fmt.Sprintf("copy %s %s\n", filepath.Join(dir, testNames[1]), filepath.Join(mirror, testNames[1]))
+	if stdout.String() != want {
+		t.Errorf("sync -dry-run printed %q, want %q", stdout, want)
+	}
+	if _, err := os.Stat(mirror); !os.IsNotExist(err) {
+		t.Errorf("sync -dry-run created the mirror")
+	}
+
+	stdout.Reset()
+	if code := run([]string{"sync", "-config", configfile}, stdout, stderr); code != 0 {
+		t.Fatalf("sync failed with code %d: %s", code, stderr)
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(mirror, testNames[1])); err != nil || string(b) != testNames[1] {
+		t.Errorf("mirror has %q, %v", b, err)
+	}
+}
+
+func TestRunUpload(t *testing.T) {
+	dir, _ := testConfig(t, testNames...)
+	defer os.RemoveAll(dir)
+	if err := dblist.MarkUploaded(filepath.Join(dir, testNames[0])); err != nil {
+		t.Skipf("file system doesn't keep upload marks: %v", err)
+	}
+	remoteDir := filepath.Join(dir, "remote")
+	b, _ := json.Marshal(map[string]interface{}{
+		"version":   2,
+		"databases": []dblist.ConfigLine{{Path: dir, Filename: "db", Suffix: "-FULL.bak"}, {Path: dir, Filename: "db", Suffix: "-differ.bak"}},
+		"upload":    dblist.UploadConfig{To: remoteDir, Concurrency: 2},
+	})
+	configfile := filepath.Join(dir, "upload.json")
+	if err := ioutil.WriteFile(configfile, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if code := run([]string{"upload", "-config", configfile, "-dry-run"}, stdout, stderr); code != 0 {
+		t.Fatalf("upload -dry-run failed with code %d: %s", code, stderr)
+	}
+	if n := strings.Count(stdout.String(), "\n"); n != 3 {
+		t.Errorf("upload -dry-run printed %d files, want 3 not uploaded files:\n%s", n, stdout)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"upload", "-config", configfile, "-json"}, stdout, stderr); code != 0 {
+		t.Fatalf("upload failed with code %d: %s", code, stderr)
+	}
+	uploaded := []fileEntry{}
+	if err := json.Unmarshal(stdout.Bytes(), &uploaded); err != nil || len(uploaded) != 3 || !uploaded[0].Uploaded {
+		t.Errorf("upload printed %s, %v, want 3 uploaded files", stdout, err)
+	}
+	if _, err := os.Stat(filepath.Join(remoteDir, testNames[1])); err != nil {
+		t.Error(err)
+	}
+
+	stdout.Reset()
+	run([]string{"upload", "-config", configfile, "-dry-run"}, stdout, stderr)
+	if stdout.Len() != 0 {
+		t.Errorf("upload -dry-run after upload printed %s", stdout)
+	}
+}
+
+func TestRunNotify(t *testing.T) {
+	dir, _ := testConfig(t, testNames...)
+	defer os.RemoveAll(dir)
+
+	var got []dblist.Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		events := []dblist.Event{}
+		json.NewDecoder(r.Body).Decode(&events)
+		got = append(got, events...)
+	}))
+	defer srv.Close()
+
+	b, _ := json.Marshal(map[string]interface{}{
+		"version":   2,
+		"databases": []dblist.ConfigLine{{Path: dir, Filename: "db", Suffix: "-FULL.bak"}},
+		"notify":    []dblist.NotifierConfig{{Type: "webhook", URL: srv.URL}},
+	})
+	configfile := filepath.Join(dir, "notify.json")
+	if err := ioutil.WriteFile(configfile, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if code := run([]string{"prune", "-config", configfile, "-notify"}, stdout, stderr); code != 0 {
+		t.Fatalf("prune failed with code %d: %s", code, stderr)
+	}
+	if len(got) != 1 || got[0].Kind != dblist.EventPruned || len(got[0].Files) != 1 {
+		t.Errorf("prune notified %v", got)
+	}
+
+	got = nil
+	if code := run([]string{"notify", "-config", configfile}, stdout, stderr); code != 0 {
+		t.Fatalf("notify failed with code %d: %s", code, stderr)
+	}
+	if len(got) != 1 || got[0].Kind != dblist.EventUncovered {
+		t.Errorf("notify sent %v", got)
+	}
+}
+
+func TestRunOverrides(t *testing.T) {
+	dir, _ := testConfig(t, testNames...)
+	defer os.RemoveAll(dir)
+	configfile := filepath.Join(dir, "vars.json")
+	config := `[{"Path":"${BACKUPS}", "Filename":"other", "Suffix":"-FULL.bak"}, {"Path":".", "Filename":"db", "Suffix":"-FULL.bak"}]`
+	if err := ioutil.WriteFile(configfile, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	args := []string{"latest", "-config", configfile, "-var", "BACKUPS=" + dir, "-root", dir}
+	if code := run(args, stdout, stderr); code != 0 {
+		t.Fatalf("run(%v) = %d, stderr: %s", args, code, stderr)
+	}
+	want := filepath.Join(dir, "other_2021-08-10T21-00-00-001-FULL.bak") + "\n" +
+		filepath.Join(dir, "db_2021-08-08T21-00-00-001-FULL.bak") + "\n"
+	if stdout.String() != want {
+		t.Errorf("run(%v) printed\n%s\nwant\n%s", args, stdout, want)
+	}
+
+	stderr.Reset()
+	if code := run([]string{"latest", "-config", configfile}, stdout, stderr); code != 1 || !strings.Contains(stderr.String(), "undefined variable BACKUPS") {
+		t.Errorf("run() without -var = %d, stderr: %s", code, stderr)
+	}
+}
+
+func TestRunLint(t *testing.T) {
+	dir, configfile := testConfig(t, testNames...)
+	defer os.RemoveAll(dir)
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if code := run([]string{"list", "-config", configfile, "-json"}, stdout, stderr); code != 0 {
+		t.Fatalf("list failed with code %d: %s", code, stderr)
+	}
+	listing := filepath.Join(dir, "listing.json")
+	if err := ioutil.WriteFile(listing, stdout.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range testNames {
+		os.Remove(filepath.Join(dir, name)) // lint must read the listing
+	}
+
+	stdout.Reset()
+	if code := run([]string{"lint", "-config", configfile, "-listing", listing}, stdout, stderr); code != 1 {
+		t.Fatalf("lint = %d, want 1, stderr: %s", code, stderr)
+	}
+	if want := "unconfigured: database other has 1 files in " + dir + " but no config line\n"; stdout.String() != want {
+		t.Errorf("lint printed\n%s\nwant\n%s", stdout, want)
+	}
+	if code := run([]string{"prune", "-config", configfile, "-listing", listing}, stdout, stderr); code != 1 {
+		t.Errorf("prune of a listing = %d, want 1", code)
+	}
+}
+
+func TestRunHistory(t *testing.T) {
+	dir, configfile := testConfig(t, testNames...)
+	defer os.RemoveAll(dir)
+	catalogfile := filepath.Join(dir, "catalog.db")
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if code := run([]string{"prune", "-config", configfile, "-catalog", catalogfile}, stdout, stderr); code != 0 {
+		t.Fatalf("prune failed with code %d: %s", code, stderr)
+	}
+	if code := run([]string{"list", "-config", configfile, "-catalog", catalogfile}, stdout, stderr); code != 0 {
+		t.Fatalf("list failed with code %d: %s", code, stderr)
+	}
+
+	stdout.Reset()
+	args := []string{"history", "-catalog", catalogfile, "-at", time.Now().Format("2006-01-02"), "db"}
+	if code := run(args, stdout, stderr); code != 0 {
+		t.Fatalf("history failed with code %d: %s", code, stderr)
+	}
+	if n := strings.Count(stdout.String(), "\n"); n != 4 {
+		t.Errorf("history printed %d files, want 4 files of db\n%s", n, stdout)
+	}
+	if n := strings.Count(stdout.String(), "deleted"); n != 2 {
+		t.Errorf("history printed %d deleted files, want 2\n%s", n, stdout)
+	}
+}
+
+func TestRunDaemon(t *testing.T) {
+	dir, _ := testConfig(t, testNames...)
+	defer os.RemoveAll(dir)
+	configfile := filepath.Join(dir, "daemon.json")
+	reportfile := filepath.Join(dir, "report.csv")
+	config := fmt.Sprintf(`{"version": 2, "defaults": {"path": %q, "days": 1},
+ "databases": [{"Filename":"db", "Suffix":"-FULL.bak"}, {"Filename":"db", "Suffix":"-differ.bak"}],
+ "daemon": {"scan": "every 1h", "verify": "every 1h", "report": "every 1h", "prune": "every 1h", "reportfile": %q}}`,
+		dir, reportfile)
+	if err := ioutil.WriteFile(configfile, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// jobs missed since the last run of the daemon run at start
+	statefile := filepath.Join(dir, "state.json")
+	state := `{"jobs": {"scan": {"last_run": "2021-08-10T00:00:00Z"}, "verify": {"last_run": "2021-08-10T00:00:00Z"},
+ "report": {"last_run": "2021-08-10T00:00:00Z"}, "prune": {"last_run": "2021-08-10T00:00:00Z"}}}`
+	if err := ioutil.WriteFile(statefile, []byte(state), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &options{config: configfile, state: statefile, keep: 1, maxPercent: 100}
+	stop := make(chan os.Signal)
+	done := make(chan error)
+	stdout := &bytes.Buffer{}
+	go func() { done <- runDaemon(opts, stdout, stop) }()
+
+	var got daemonState
+	for deadline := time.Now().Add(10 * time.Second); ; time.Sleep(50 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("jobs didn't run, state %+v", got)
+		}
+		b, err := ioutil.ReadFile(statefile)
+		if err != nil || json.Unmarshal(b, &got) != nil {
+			continue
+		}
+		if js := got.Jobs["prune"]; js != nil && js.LastRun.Year() > 2021 {
+			break
+		}
+	}
+	other := &options{config: configfile, state: statefile}
+	if err := runDaemon(other, ioutil.Discard, stop); !errors.Is(err, dblist.ErrLocked) {
+		t.Errorf("second daemon error = %v, want %v", err, dblist.ErrLocked)
+	}
+	stop <- os.Interrupt
+	if err := <-done; err != nil {
+		t.Fatalf("runDaemon() error = %v", err)
+	}
+
+	for name, js := range got.Jobs {
+		if js.Error != "" {
+			t.Errorf("job %s failed: %s\n%s", name, js.Error, stdout)
+		}
+	}
+	if _, err := os.Stat(reportfile); err != nil {
+		t.Errorf("report job didn't write report: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, testNames[0])); !os.IsNotExist(err) {
+		t.Errorf("prune job didn't delete %s", testNames[0])
+	}
+}
@@ -0,0 +1,514 @@
+package dblist
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ConfigVersion is the current version of config document.
+// Version 1 is a bare json array of config lines.
+const ConfigVersion = 2
+
+// Config is a config document.
+// ex. {"version": 2,
+// "defaults": {"path": "g:/ShebB", "days": 1, "timezone": "Europe/Moscow"},
+// "databases": [{"Filename":"buh_log8", "Suffix":"-FULL.bak", "Days":7}],
+// "notify": [{"Type":"webhook", "URL":"http://alerts/dblist"}],
+// "include": ["servers/*.json"],
+// "daemon": {"scan": "every 15m", "verify": "daily at 08:00", "prune": "daily at 03:00"},
+// "mirrors": [{"To": "/mnt/offsite/sheb", "Keep": 2, "Prune": true}],
+// "upload": {"To": "/mnt/cloud/sheb", "Concurrency": 2, "BytesPerSecond": 1048576}}
+type Config struct {
+	Version   int
+	Defaults  ConfigDefaults
+	Databases []ConfigLine
+	Notify    []NotifierConfig
+	Include   []string // config files or glob patterns relative to the including file
+	Daemon    DaemonConfig
+	Mirrors   []MirrorConfig
+	Upload    UploadConfig
+}
+
+// ConfigDefaults are used by config lines that have no such values.
+type ConfigDefaults struct {
+	Path     string
+	Days     int
+	Schedule string
+	Timezone string // IANA time zone of times in file names, local time zone if empty
+}
+
+// DaemonConfig is "daemon" section of a config file with schedules of 'dblist daemon' jobs, see ParseSchedule.
+// A job with an empty schedule doesn't run. Schedules are in config time zone.
+type DaemonConfig struct {
+	Scan       string // reads config paths, other jobs use the latest scan
+	Verify     string
+	Report     string
+	Prune      string // prune always reads config paths again
+	Sync       string // copies the newest files to mirrors
+	Upload     string // uploads files not uploaded yet
+	ReportFile string // report job writes this file, its format is chosen by extension: .json, .csv or markdown
+}
+
+// MirrorConfig is a directory that keeps copies of the newest backup files of config paths, see package remote.
+type MirrorConfig struct {
+	To     string // a directory, its path is expanded like config line paths, see remote.SourceDirs
+	Keep   uint   // copies of every group in the mirror, 1 if zero
+	Prune  bool   // delete mirror files of configured groups that are not among Keep newest
+	Verify bool   // compare checksums of files already in the mirror, not only sizes
+}
+
+// UploadConfig is "upload" section of a config file, see remote.Uploader.
+// Backup files not uploaded yet are uploaded to To and marked uploaded, see MarkUploaded.
+type UploadConfig struct {
+	To             string // a directory, ex. a mounted cloud storage or a share of the main office
+	Concurrency    int    // files uploaded at once, 1 if zero
+	BytesPerSecond int64  // limit of all uploads together, no limit if zero
+	ChunkSize      int    // bytes written at once, a failed upload resumes after written chunks
+	Retries        int    // resumes of a failed upload, remote.DefaultRetries if zero, no retries if negative
+}
+
+// ConfigError is an error in a config file with its position.
+type ConfigError struct {
+	Filename string
+	Line     int // 1-based, 0 if unknown
+	Column   int // 1-based, in runes
+	Err      error
+}
+
+func (e *ConfigError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %v", e.Filename, e.Err)
+	}
+	return fmt.Sprintf("%s:%d:%d: %v", e.Filename, e.Line, e.Column, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// ReadConfigFile reads and validates a config document.
+// Config file format is chosen by file extension: .yaml or .yml for YAML, .toml for TOML, json otherwise.
+// A bare array of config lines is read as version 1 document.
+// Unknown fields, negative Days, empty Filename, bad Schedule,
+// duplicate Filename with Suffix and bad notifiers are errors of type *ConfigError.
+// Defaults are applied to config lines.
+// Included files are read and their config lines and notifiers are appended to the document.
+// Defaults of the including file apply to values that included config lines don't have.
+// Time zone, daemon, mirrors and upload sections of included files are ignored.
+// Paths and includes are expanded with environment variables, see ReadConfigFileWith.
+func ReadConfigFile(filename string) (*Config, error) {
+	return ReadConfigFileWith(filename, ConfigOverrides{})
+}
+
+// ReadConfigFileWith reads a config file like ReadConfigFile and applies overrides.
+// ${VAR} in paths of config lines, defaults and includes is replaced by a variable
+// from overrides or from environment. An undefined variable is an error.
+// Relative paths of config lines are joined to the root directory, see ConfigOverrides.
+func ReadConfigFileWith(filename string, o ConfigOverrides) (*Config, error) {
+	c, err := readConfigFile(filename, o, nil)
+	if err != nil {
+		return nil, err
+	}
+	// time zones of included files are ignored, all lines get the time zone of the including file
+	var loc *time.Location
+	if c.Defaults.Timezone != "" {
+		loc = c.Location()
+	}
+	for i := range c.Databases {
+		c.Databases[i].Location = loc
+	}
+	if err := o.apply(c); err != nil {
+		return nil, &ConfigError{Filename: filename, Err: err}
+	}
+	return c, nil
+}
+
+// readConfigFile reads a config file and its includes.
+// including are absolute names of files that include this file, they are used to detect cycles.
+func readConfigFile(filename string, o ConfigOverrides, including []string) (*Config, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range including {
+		if f == abs {
+			return nil, &ConfigError{Filename: filename, Err: fmt.Errorf("config includes itself via %s", strings.Join(including, " -> "))}
+		}
+	}
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var c *Config
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		c, err = ParseYAMLConfig(b, filename)
+	case ".toml":
+		c, err = ParseTOMLConfig(b, filename)
+	default:
+		c, err = ParseConfig(b, filename)
+	}
+	if err != nil || len(c.Include) == 0 {
+		return c, err
+	}
+
+	including = append(including[:len(including):len(including)], abs)
+	sources := make(map[[2]string]string) // Filename and Suffix to the file with such config line
+	for _, line := range c.Databases {
+		sources[[2]string{line.Filename, line.Suffix}] = filename
+	}
+	for _, pattern := range c.Include {
+		pattern, err := o.expand(pattern)
+		if err != nil {
+			return nil, &ConfigError{Filename: filename, Err: fmt.Errorf("include: %w", err)}
+		}
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(filename), pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, &ConfigError{Filename: filename, Err: fmt.Errorf("bad include %q: %w", pattern, err)}
+		}
+		if len(matches) == 0 && !IsGlobPath(pattern) {
+			return nil, &ConfigError{Filename: filename, Err: fmt.Errorf("included file %s not found", pattern)}
+		}
+		for _, m := range matches {
+			inc, err := readConfigFile(m, o, including)
+			if err != nil {
+				return nil, err
+			}
+			for _, line := range inc.Databases {
+				applyDefaults(&line, c.Defaults)
+				key := [2]string{line.Filename, line.Suffix}
+				if src, ok := sources[key]; ok {
+					return nil, &ConfigError{Filename: m, Err: fmt.Errorf("config line %s%s duplicates a line in %s", line.Filename, line.Suffix, src)}
+				}
+				sources[key] = m
+				c.Databases = append(c.Databases, line)
+			}
+			c.Notify = append(c.Notify, inc.Notify...)
+		}
+	}
+	return c, nil
+}
+
+// ParseConfig parses and validates a config document, see ReadConfigFile.
+// filename is used in errors only.
+func ParseConfig(b []byte, filename string) (*Config, error) {
+	b = bytes.TrimPrefix(b, []byte("\xEF\xBB\xBF")) // skip BOM
+	return parseJSONConfig(b, filename, nil)
+}
+
+// parseJSONConfig parses json converted from other formats.
+// position maps json offsets to positions in the original file.
+func parseJSONConfig(b []byte, filename string, position func(offset int64) (line, column int)) (*Config, error) {
+	p := &configParser{b: b, filename: filename, position: position, dec: json.NewDecoder(bytes.NewReader(b))}
+	p.dec.DisallowUnknownFields()
+	return p.parse()
+}
+
+// Location returns time zone of times in file names.
+func (c *Config) Location() *time.Location {
+	loc, err := time.LoadLocation(c.Defaults.Timezone)
+	if err != nil || c.Defaults.Timezone == "" {
+		return time.Local // Timezone is validated by ParseConfig
+	}
+	return loc
+}
+
+// WallClock returns wall clock of t in config time zone in UTC location, see WallClock.
+func (c *Config) WallClock(t time.Time) time.Time {
+	return WallClockIn(t, c.Location())
+}
+
+// configParser decodes a config document remembering positions of its elements.
+type configParser struct {
+	b        []byte
+	filename string
+	position func(offset int64) (line, column int) // nil for json files
+	dec      *json.Decoder
+}
+
+// errorAt returns *ConfigError with position of byte offset.
+func (p *configParser) errorAt(offset int64, err error) error {
+	if offset < 0 || offset > int64(len(p.b)) {
+		return &ConfigError{Filename: p.filename, Err: err}
+	}
+	if p.position != nil {
+		line, column := p.position(offset)
+		return &ConfigError{Filename: p.filename, Line: line, Column: column, Err: err}
+	}
+	before := p.b[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := utf8.RuneCount(before[bytes.LastIndexByte(before, '\n')+1:]) + 1
+	return &ConfigError{Filename: p.filename, Line: line, Column: column, Err: err}
+}
+
+// decodeErr converts json errors to *ConfigError.
+// start is the offset of the decoded value.
+func (p *configParser) decodeErr(start int64, err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return p.errorAt(syntaxErr.Offset-1, err) // Offset is after the bad character
+	case errors.As(err, &typeErr):
+		return p.errorAt(start+typeErr.Offset, err)
+	}
+	return p.errorAt(start, err)
+}
+
+// valueStart returns offset of the next value skipping spaces and a separator.
+func (p *configParser) valueStart() int64 {
+	off := p.dec.InputOffset()
+	for off < int64(len(p.b)) && strings.IndexByte(" \t\r\n,:", p.b[off]) != -1 {
+		off++
+	}
+	return off
+}
+
+func (p *configParser) parse() (*Config, error) {
+	c := &Config{}
+	start := p.valueStart()
+	tok, err := p.dec.Token()
+	if err != nil {
+		return nil, p.decodeErr(start, fmt.Errorf("config has bad json structure: %w", err))
+	}
+	var positions []int64
+	switch tok {
+	case json.Delim('['):
+		c.Version = 1
+		if c.Databases, positions, err = p.parseLines(); err != nil {
+			return nil, err
+		}
+	case json.Delim('{'):
+		if positions, err = p.parseObject(c); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, p.errorAt(start, errors.New("config must be a json object or array"))
+	}
+	if end := p.valueStart(); end != int64(len(p.b)) {
+		return nil, p.errorAt(end, errors.New("config has data after its end"))
+	}
+
+	if err := p.validate(c, positions); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// parseObject decodes fields of a version 2 document.
+func (p *configParser) parseObject(c *Config) (positions []int64, err error) {
+	objStart := p.dec.InputOffset() - 1
+	for p.dec.More() {
+		keyStart := p.valueStart()
+		tok, err := p.dec.Token()
+		if err != nil {
+			return nil, p.decodeErr(keyStart, err)
+		}
+		key, _ := tok.(string)
+		start := p.valueStart()
+		switch {
+		case strings.EqualFold(key, "version"):
+			err = p.dec.Decode(&c.Version)
+		case strings.EqualFold(key, "defaults"):
+			err = p.dec.Decode(&c.Defaults)
+			if err == nil {
+				err = validateDefaults(c.Defaults)
+			}
+		case strings.EqualFold(key, "databases"):
+			err = p.expectArray()
+			if err == nil {
+				c.Databases, positions, err = p.parseLines()
+				if err != nil {
+					return nil, err
+				}
+			}
+		case strings.EqualFold(key, "include"):
+			err = p.dec.Decode(&c.Include)
+		case strings.EqualFold(key, "mirrors"):
+			err = p.dec.Decode(&c.Mirrors)
+			for i := 0; err == nil && i < len(c.Mirrors); i++ {
+				if c.Mirrors[i].To == "" {
+					err = fmt.Errorf("mirror %d has empty To", i+1)
+				}
+			}
+		case strings.EqualFold(key, "upload"):
+			if err = p.dec.Decode(&c.Upload); err == nil {
+				err = validateUpload(c.Upload)
+			}
+		case strings.EqualFold(key, "daemon"):
+			err = p.dec.Decode(&c.Daemon)
+			if err == nil {
+				err = validateDaemon(c.Daemon)
+			}
+		case strings.EqualFold(key, "notify"):
+			err = p.expectArray()
+			for err == nil && p.dec.More() {
+				n := NotifierConfig{}
+				notifyStart := p.valueStart()
+				if err = p.dec.Decode(&n); err != nil {
+					return nil, p.decodeErr(notifyStart, err)
+				}
+				if _, err = NewNotifier(n); err != nil {
+					return nil, p.errorAt(notifyStart, err)
+				}
+				c.Notify = append(c.Notify, n)
+			}
+			if err == nil {
+				_, err = p.dec.Token() // ]
+			}
+		default:
+			return nil, p.errorAt(keyStart, fmt.Errorf("unknown field %q", key))
+		}
+		if err != nil {
+			return nil, p.decodeErr(start, err)
+		}
+	}
+	if _, err := p.dec.Token(); err != nil { // }
+		return nil, p.decodeErr(p.valueStart(), err)
+	}
+
+	switch {
+	case c.Version == 0:
+		return nil, p.errorAt(objStart, fmt.Errorf("config has no version, current version is %d", ConfigVersion))
+	case c.Version != ConfigVersion:
+		return nil, p.errorAt(objStart, fmt.Errorf("config version %d is not supported", c.Version))
+	}
+	return positions, nil
+}
+
+func (p *configParser) expectArray() error {
+	tok, err := p.dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != json.Delim('[') {
+		return errors.New("array expected")
+	}
+	return nil
+}
+
+// parseLines decodes config lines after [ and the closing ].
+func (p *configParser) parseLines() ([]ConfigLine, []int64, error) {
+	lines := []ConfigLine{}
+	positions := []int64{}
+	for p.dec.More() {
+		line := ConfigLine{}
+		start := p.valueStart()
+		if err := p.dec.Decode(&line); err != nil {
+			return nil, nil, p.decodeErr(start, err)
+		}
+		lines = append(lines, line)
+		positions = append(positions, start)
+	}
+	if _, err := p.dec.Token(); err != nil { // ]
+		return nil, nil, p.decodeErr(p.valueStart(), err)
+	}
+	return lines, positions, nil
+}
+
+func validateDefaults(d ConfigDefaults) error {
+	if d.Days < 0 {
+		return fmt.Errorf("defaults have negative Days %d", d.Days)
+	}
+	if d.Schedule != "" {
+		if _, err := ParseSchedule(d.Schedule); err != nil {
+			return err
+		}
+	}
+	if _, err := time.LoadLocation(d.Timezone); err != nil {
+		return fmt.Errorf("defaults have bad Timezone: %w", err)
+	}
+	return nil
+}
+
+func validateDaemon(d DaemonConfig) error {
+	for _, sch := range []string{d.Scan, d.Verify, d.Report, d.Prune, d.Sync, d.Upload} {
+		if sch == "" {
+			continue
+		}
+		if _, err := ParseSchedule(sch); err != nil {
+			return fmt.Errorf("daemon: %w", err)
+		}
+	}
+	return nil
+}
+
+func validateUpload(u UploadConfig) error {
+	switch {
+	case u.To == "":
+		return errors.New("upload has empty To")
+	case u.Concurrency < 0 || u.BytesPerSecond < 0 || u.ChunkSize < 0:
+		return errors.New("upload has negative Concurrency, BytesPerSecond or ChunkSize")
+	}
+	return nil
+}
+
+// applyDefaults sets empty values of a config line from defaults.
+func applyDefaults(line *ConfigLine, d ConfigDefaults) {
+	if line.Path == "" {
+		line.Path = d.Path
+	}
+	if line.Days == 0 {
+		line.Days = d.Days
+	}
+	if line.Schedule == "" {
+		line.Schedule = d.Schedule
+	}
+}
+
+// validate applies defaults to config lines and checks them.
+func (p *configParser) validate(c *Config, positions []int64) error {
+	seen := make(map[[2]string]int)
+	for i := range c.Databases {
+		line := &c.Databases[i]
+		applyDefaults(line, c.Defaults)
+		if c.Defaults.Timezone != "" {
+			line.Location = c.Location()
+		}
+
+		var err error
+		switch {
+		case line.Filename == "":
+			err = errors.New("config line has empty Filename")
+		case line.Days < 0:
+			err = fmt.Errorf("config line %s%s has negative Days %d", line.Filename, line.Suffix, line.Days)
+		}
+		if err == nil && IsDBNamePattern(line.Filename) {
+			if errp := ValidateDBNamePattern(line.Filename); errp != nil {
+				err = fmt.Errorf("config line %s%s has bad Filename pattern: %w", line.Filename, line.Suffix, errp)
+			}
+		}
+		if err == nil && IsGlobPath(line.Path) {
+			if _, errm := filepath.Match(line.Path, line.Path); errm != nil {
+				err = fmt.Errorf("config line %s%s has bad Path pattern %q: %w", line.Filename, line.Suffix, line.Path, errm)
+			}
+		}
+		if err == nil && line.Schedule != "" {
+			if _, errsch := ParseSchedule(line.Schedule); errsch != nil {
+				err = fmt.Errorf("config line %s%s: %w", line.Filename, line.Suffix, errsch)
+			}
+		}
+		key := [2]string{line.Filename, line.Suffix}
+		if prev, ok := seen[key]; ok && err == nil {
+			err = fmt.Errorf("config line %s%s duplicates another line", line.Filename, line.Suffix)
+			if prevErr := p.errorAt(positions[prev], nil).(*ConfigError); prevErr.Line != 0 {
+				err = fmt.Errorf("config line %s%s duplicates line at %d:%d", line.Filename, line.Suffix, prevErr.Line, prevErr.Column)
+			}
+		}
+		if err != nil {
+			return p.errorAt(positions[i], err)
+		}
+		seen[key] = i
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package dblist
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    *Config
+		wantErr string // error prefix
+	}{
+		{name: "version 1",
+			config: "\xEF\xBB\xBF[{\"path\":\"g:/ShebB\", \"Filename\":\"buh_log8\", \"Days\":1}]\n",
+			want: &Config{Version: 1, Databases: []ConfigLine{
+				{Path: "g:/ShebB", Filename: "buh_log8", Days: 1},
+			}},
+		},
+		{name: "version 2",
+			config: `{"version": 2,
+ "defaults": {"path": "/mnt/sheb", "days": 1, "timezone": "UTC"},
+ "databases": [
+  {"Filename":"buh_log8", "Suffix":"-FULL.bak", "Days":7, "Schedule":"weekly Sunday"},
+  {"Filename":"buh_log8", "Suffix":"-differ.bak", "Path":"/mnt/other"}
+ ],
+ "notify": [{"Type":"webhook", "URL":"http://alerts/dblist"}]}`,
+			want: &Config{
+				Version:  2,
+				Defaults: ConfigDefaults{Path: "/mnt/sheb", Days: 1, Timezone: "UTC"},
+				Databases: []ConfigLine{
+					{Path: "/mnt/sheb", Filename: "buh_log8", Suffix: "-FULL.bak", Days: 7, Schedule: "weekly Sunday", Location: time.UTC},
+					{Path: "/mnt/other", Filename: "buh_log8", Suffix: "-differ.bak", Days: 1, Location: time.UTC},
+				},
+				Notify: []NotifierConfig{{Type: "webhook", URL: "http://alerts/dblist"}},
+			},
+		},
+		{name: "unknown field in line",
+			config:  "[\n {\"Filename\":\"a\"},\n {\"Filename\":\"b\", \"Dayz\":1}]",
+			wantErr: `c.json:3:2: json: unknown field "Dayz"`,
+		},
+		{name: "unknown field",
+			config:  "{\"version\": 2,\n \"database\": []}",
+			wantErr: `c.json:2:2: unknown field "database"`,
+		},
+		{name: "negative Days",
+			config:  "{\"version\": 2, \"databases\": [\n\t{\"Filename\":\"a\", \"Days\":-1}]}",
+			wantErr: "c.json:2:2: config line a has negative Days -1",
+		},
+		{name: "empty Filename",
+			config:  `[{"Filename":"a"}, {"Suffix":"-FULL.bak"}]`,
+			wantErr: "c.json:1:20: config line has empty Filename",
+		},
+		{name: "duplicate",
+			config:  "[{\"Filename\":\"зп\", \"Suffix\":\".bak\"},\n {\"Filename\":\"зп\", \"Suffix\":\".bak\", \"Path\":\"other\"}]",
+			wantErr: "c.json:2:2: config line зп.bak duplicates line at 1:2",
+		},
+		{name: "bad type",
+			config:  "[{\"Filename\":\"a\",\n \"Days\":\"1\"}]",
+			wantErr: "c.json:2:12: json: cannot unmarshal string",
+		},
+		{name: "syntax error",
+			config:  "[{\"Filename\":\"a\"},\n {\"Filename\" \"b\"}]",
+			wantErr: "c.json:2:14: invalid character '\"' after object key",
+		},
+		{name: "no version",
+			config:  `{"databases": []}`,
+			wantErr: "c.json:1:1: config has no version",
+		},
+		{name: "future version",
+			config:  `{"version": 3}`,
+			wantErr: "c.json:1:1: config version 3 is not supported",
+		},
+		{name: "bad schedule",
+			config:  `[{"Filename":"a", "Schedule":"sometimes"}]`,
+			wantErr: `c.json:1:2: config line a: schedule "sometimes" is not recognized`,
+		},
+		{name: "bad timezone",
+			config:  `{"version": 2, "defaults": {"timezone": "Mars/Olympus"}}`,
+			wantErr: "c.json:1:28: defaults have bad Timezone",
+		},
+		{name: "bad notifier",
+			config:  `{"version": 2, "notify": [{"Type":"pigeon"}]}`,
+			wantErr: `c.json:1:27: unknown notifier type "pigeon"`,
+		},
+		{name: "daemon",
+			config: `{"version": 2, "daemon": {"scan": "every 15m", "prune": "daily at 03:00", "reportfile": "report.md"}}`,
+			want: &Config{Version: 2, Daemon: DaemonConfig{Scan: "every 15m", Prune: "daily at 03:00", ReportFile: "report.md"}},
+		},
+		{name: "bad daemon schedule",
+			config:  `{"version": 2, "daemon": {"verify": "hourly"}}`,
+			wantErr: `c.json:1:26: daemon: schedule "hourly" is not recognized`,
+		},
+		{name: "mirrors",
+			config: `{"version": 2, "mirrors": [{"To": "/mnt/offsite", "Keep": 2, "Prune": true}]}`,
+			want:   &Config{Version: 2, Mirrors: []MirrorConfig{{To: "/mnt/offsite", Keep: 2, Prune: true}}},
+		},
+		{name: "mirror without To",
+			config:  `{"version": 2, "mirrors": [{"Keep": 2}]}`,
+			wantErr: `c.json:1:27: mirror 1 has empty To`,
+		},
+		{name: "upload",
+			config: `{"version": 2, "upload": {"To": "/mnt/cloud", "Concurrency": 2, "BytesPerSecond": 1048576}}`,
+			want:   &Config{Version: 2, Upload: UploadConfig{To: "/mnt/cloud", Concurrency: 2, BytesPerSecond: 1048576}},
+		},
+		{name: "bad upload",
+			config:  `{"version": 2, "upload": {"To": "/mnt/cloud", "Concurrency": -1}}`,
+			wantErr: `c.json:1:26: upload has negative Concurrency, BytesPerSecond or ChunkSize`,
+		},
+		{name: "data after end",
+			config:  `[] []`,
+			wantErr: "c.json:1:4: config has data after its end",
+		},
+		{name: "bad path pattern",
+			config:  `[{"Filename":"a", "Path":"/backups/[mssql"}]`,
+			wantErr: `c.json:1:2: config line a has bad Path pattern "/backups/[mssql"`,
+		},
+		{name: "bad filename pattern",
+			config:  `[{"Filename":"^tenant_(\\d+$"}]`,
+			wantErr: "c.json:1:2: config line ^tenant_(\\d+$ has bad Filename pattern",
+		},
+		{name: "not a config",
+			config:  `"config"`,
+			wantErr: "c.json:1:1: config must be a json object or array",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseConfig([]byte(tt.config), "c.json")
+			if tt.wantErr != "" {
+				var confErr *ConfigError
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) || !errors.As(err, &confErr) {
+					t.Errorf("ParseConfig() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseConfig() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseConfig() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigWallClock(t *testing.T) {
+	c, err := ParseConfig([]byte(`{"version": 2, "defaults": {"timezone": "Europe/Moscow"}}`), "c.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := c.WallClock(mustparse("2021-08-06T12-00-00"))
+	if want := mustparse("2021-08-06T15-00-00"); !got.Equal(want) {
+		t.Errorf("WallClock() = %v, want %v", got, want)
+	}
+}
+
+func TestReadConfigFileInclude(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dblist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, content string) {
+		filename := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("dblist.json", `{"version": 2,
+ "defaults": {"path": "/backups/*/mssql", "days": 1},
+ "databases": [{"Filename":"master", "Suffix":"-FULL.bak", "Days":7}],
+ "include": ["servers/*.json", "servers/*.yaml"]}`)
+	write("servers/a.json", `[{"Filename":"buh_log8", "Suffix":"-FULL.bak"}]`)
+	write("servers/b.yaml", "version: 2\ndatabases:\n  - {Filename: zp, Path: /mnt/zp}\nnotify:\n  - {Type: webhook, URL: http://alerts}\n")
+
+	got, err := ReadConfigFile(filepath.Join(dir, "dblist.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ConfigLine{
+		{Path: "/backups/*/mssql", Filename: "master", Suffix: "-FULL.bak", Days: 7},
+		{Path: "/backups/*/mssql", Filename: "buh_log8", Suffix: "-FULL.bak", Days: 1},
+		{Path: "/mnt/zp", Filename: "zp", Days: 1},
+	}
+	if !reflect.DeepEqual(got.Databases, want) {
+		t.Errorf("ReadConfigFile() = %+v, want %+v", got.Databases, want)
+	}
+	if len(got.Notify) != 1 {
+		t.Errorf("ReadConfigFile() notify = %+v, want the included notifier", got.Notify)
+	}
+
+	write("servers/c.json", `[{"Filename":"master", "Suffix":"-FULL.bak"}]`)
+	_, err = ReadConfigFile(filepath.Join(dir, "dblist.json"))
+	if err == nil || !strings.Contains(err.Error(), "config line master-FULL.bak duplicates a line in") {
+		t.Errorf("ReadConfigFile() error = %v, want duplicate", err)
+	}
+	os.Remove(filepath.Join(dir, "servers/c.json"))
+
+	write("servers/c.json", `{"version": 2, "include": ["../dblist.json"]}`)
+	_, err = ReadConfigFile(filepath.Join(dir, "dblist.json"))
+	if err == nil || !strings.Contains(err.Error(), "config includes itself") {
+		t.Errorf("ReadConfigFile() error = %v, want include cycle", err)
+	}
+}
+
+func TestReadConfigFileIncludeTimezone(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dblist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, content string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.json", `{"version": 2, "databases": [{"Filename":"zp"}], "defaults": {"timezone": "Europe/Moscow"}}`)
+	for _, tt := range []struct {
+		timezone string
+		want     *time.Location
+	}{
+		{"", nil},
+		{"UTC", time.UTC},
+	} {
+		write("dblist.json", `{"version": 2, "defaults": {"timezone": "`+tt.timezone+`"},
+ "databases": [{"Filename":"buh_log8"}], "include": ["a.json"]}`)
+		c, err := ReadConfigFile(filepath.Join(dir, "dblist.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range c.Databases {
+			if line.Location != tt.want {
+				t.Errorf("timezone %q: line %s has Location %v, want %v", tt.timezone, line.Filename, line.Location, tt.want)
+			}
+		}
+	}
+}
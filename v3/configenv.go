@@ -0,0 +1,97 @@
+package dblist
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EnvRoot is an environment variable with the root directory of relative config paths.
+const EnvRoot = "DBLIST_ROOT"
+
+// ConfigOverrides adapt one config file to different machines.
+// ex. a config line with path "${DBLIST_ROOT}/ShebB" or just "ShebB"
+// becomes g:/ShebB on windows with DBLIST_ROOT=g:/ and /mnt/ShebB on linux with DBLIST_ROOT=/mnt.
+type ConfigOverrides struct {
+	Root string            // root of relative paths, DBLIST_ROOT environment variable if empty
+	Vars map[string]string // variables for ${VAR}, they take precedence over environment
+}
+
+// root returns the root directory of relative paths, empty if there is no root.
+func (o ConfigOverrides) root() string {
+	if o.Root != "" {
+		return o.Root
+	}
+	return os.Getenv(EnvRoot)
+}
+
+func (o ConfigOverrides) lookup(name string) (string, bool) {
+	if v, ok := o.Vars[name]; ok {
+		return v, true
+	}
+	if name == EnvRoot && o.Root != "" {
+		return o.Root, true
+	}
+	return os.LookupEnv(name)
+}
+
+// expand replaces every ${VAR} in s.
+// Only braced variables are expanded, $ alone is a part of windows paths like \\server\d$\backups.
+func (o ConfigOverrides) expand(s string) (string, error) {
+	b := strings.Builder{}
+	for {
+		i := strings.Index(s, "${")
+		if i == -1 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		j := strings.IndexByte(s[i:], '}')
+		if j == -1 {
+			return "", fmt.Errorf("unclosed ${ in %q", s)
+		}
+		name := s[i+2 : i+j]
+		v, ok := o.lookup(name)
+		if !ok {
+			return "", fmt.Errorf("undefined variable %s", name)
+		}
+		b.WriteString(s[:i])
+		b.WriteString(v)
+		s = s[i+j+1:]
+	}
+}
+
+// path expands a config path and joins it to the root if it is relative.
+func (o ConfigOverrides) path(p string) (string, error) {
+	p, err := o.expand(p)
+	if err != nil {
+		return "", err
+	}
+	if root := o.root(); root != "" && p != "" && !filepath.IsAbs(p) {
+		p = filepath.Join(root, p)
+	}
+	return p, nil
+}
+
+// apply expands paths of defaults and config lines.
+func (o ConfigOverrides) apply(c *Config) error {
+	var err error
+	if c.Defaults.Path, err = o.path(c.Defaults.Path); err != nil {
+		return fmt.Errorf("defaults path: %w", err)
+	}
+	for i := range c.Databases {
+		line := &c.Databases[i]
+		if line.Path, err = o.path(line.Path); err != nil {
+			return fmt.Errorf("config line %s%s: %w", line.Filename, line.Suffix, err)
+		}
+	}
+	for i := range c.Mirrors {
+		if c.Mirrors[i].To, err = o.path(c.Mirrors[i].To); err != nil {
+			return fmt.Errorf("mirror %d: %w", i+1, err)
+		}
+	}
+	if c.Upload.To, err = o.path(c.Upload.To); err != nil {
+		return fmt.Errorf("upload: %w", err)
+	}
+	return nil
+}
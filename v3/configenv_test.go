@@ -0,0 +1,43 @@
+package dblist
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigOverrides(t *testing.T) {
+	os.Setenv("DBLIST_TEST_DRIVE", "/mnt")
+	defer os.Unsetenv("DBLIST_TEST_DRIVE")
+	root := filepath.FromSlash("/srv/backups")
+	tests := []struct {
+		name    string
+		o       ConfigOverrides
+		path    string
+		want    string
+		wantErr string
+	}{
+		{"environment", ConfigOverrides{}, "${DBLIST_TEST_DRIVE}/sheb", "/mnt/sheb", ""},
+		{"vars over environment", ConfigOverrides{Vars: map[string]string{"DBLIST_TEST_DRIVE": "g:"}}, "${DBLIST_TEST_DRIVE}/ShebB", "g:/ShebB", ""},
+		{"root variable", ConfigOverrides{Root: "/mnt"}, "${DBLIST_ROOT}/sheb", "/mnt/sheb", ""},
+		{"relative path", ConfigOverrides{Root: root}, "sheb", filepath.Join(root, "sheb"), ""},
+		{"windows share", ConfigOverrides{}, `\\server\d$\backups`, `\\server\d$\backups`, ""},
+		{"undefined", ConfigOverrides{}, "${DBLIST_TEST_UNDEFINED}/sheb", "", "undefined variable DBLIST_TEST_UNDEFINED"},
+		{"unclosed", ConfigOverrides{}, "${DBLIST_TEST_DRIVE/sheb", "", "unclosed ${"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.o.path(tt.path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("path(%s) error = %v, want %s", tt.path, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("path(%s) = %s, %v, want %s", tt.path, got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package dblist
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ParseYAMLConfig parses and validates a YAML config document, see ReadConfigFile.
+// Field names are the same as in json config, errors have positions in YAML file.
+// ex.
+// version: 2
+// defaults: {path: /mnt/sheb, days: 1}
+// databases:
+//   - {Filename: buh_log8, Suffix: -FULL.bak, Days: 7} # weekly full backup
+func ParseYAMLConfig(b []byte, filename string) (*Config, error) {
+	doc := yaml.Node{}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, &ConfigError{Filename: filename, Err: fmt.Errorf("config has bad yaml structure: %w", err)}
+	}
+	if len(doc.Content) == 0 {
+		return nil, &ConfigError{Filename: filename, Err: errors.New("config is empty")}
+	}
+	c := &yamlConverter{}
+	if err := c.convert(doc.Content[0]); err != nil {
+		return nil, &ConfigError{Filename: filename, Line: c.errLine, Column: c.errColumn, Err: err}
+	}
+	return parseJSONConfig(c.buf.Bytes(), filename, c.position)
+}
+
+// ParseTOMLConfig parses and validates a TOML config document, see ReadConfigFile.
+// TOML document must be a version 2 document, ex.
+// version = 2
+// [[databases]]
+// Filename = "buh_log8" # weekly full backup
+// Suffix = "-FULL.bak"
+// Days = 7
+// Validation errors of TOML config have no positions.
+func ParseTOMLConfig(b []byte, filename string) (*Config, error) {
+	v := make(map[string]interface{})
+	if _, err := toml.Decode(string(b), &v); err != nil {
+		return nil, &ConfigError{Filename: filename, Err: fmt.Errorf("config has bad toml structure: %w", err)}
+	}
+	j, err := json.Marshal(v)
+	if err != nil {
+		return nil, &ConfigError{Filename: filename, Err: err}
+	}
+	return parseJSONConfig(j, filename, func(offset int64) (int, int) { return 0, 0 })
+}
+
+// yamlMark maps an offset in converted json to a position in YAML file.
+type yamlMark struct {
+	offset       int64
+	line, column int
+}
+
+// yamlConverter converts YAML nodes to json remembering their positions.
+type yamlConverter struct {
+	buf                bytes.Buffer
+	marks              []yamlMark
+	errLine, errColumn int
+}
+
+func (c *yamlConverter) position(offset int64) (int, int) {
+	i := sort.Search(len(c.marks), func(i int) bool { return c.marks[i].offset > offset })
+	if i == 0 {
+		return 0, 0
+	}
+	return c.marks[i-1].line, c.marks[i-1].column
+}
+
+func (c *yamlConverter) fail(n *yaml.Node, err error) error {
+	c.errLine, c.errColumn = n.Line, n.Column
+	return err
+}
+
+func (c *yamlConverter) convert(n *yaml.Node) error {
+	for n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	c.marks = append(c.marks, yamlMark{offset: int64(c.buf.Len()), line: n.Line, column: n.Column})
+	switch n.Kind {
+	case yaml.MappingNode:
+		c.buf.WriteByte('{')
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i]
+			if key.Kind != yaml.ScalarNode {
+				return c.fail(key, errors.New("mapping key must be a string"))
+			}
+			if i != 0 {
+				c.buf.WriteByte(',')
+			}
+			c.marks = append(c.marks, yamlMark{offset: int64(c.buf.Len()), line: key.Line, column: key.Column})
+			k, _ := json.Marshal(key.Value)
+			c.buf.Write(k)
+			c.buf.WriteByte(':')
+			if err := c.convert(n.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		c.buf.WriteByte('}')
+	case yaml.SequenceNode:
+		c.buf.WriteByte('[')
+		for i, item := range n.Content {
+			if i != 0 {
+				c.buf.WriteByte(',')
+			}
+			if err := c.convert(item); err != nil {
+				return err
+			}
+		}
+		c.buf.WriteByte(']')
+	case yaml.ScalarNode:
+		var v interface{}
+		switch n.ShortTag() {
+		case "!!int":
+			var i int64
+			v = &i
+		case "!!float":
+			var f float64
+			v = &f
+		case "!!bool":
+			var b bool
+			v = &b
+		}
+		if v == nil || n.Decode(v) != nil {
+			v = n.Value // strings, timestamps and too big numbers
+		}
+		if n.ShortTag() == "!!null" {
+			v = nil
+		}
+		j, err := json.Marshal(v)
+		if err != nil {
+			return c.fail(n, err)
+		}
+		c.buf.Write(j)
+	default:
+		return c.fail(n, fmt.Errorf("unsupported yaml node"))
+	}
+	return nil
+}
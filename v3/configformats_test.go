@@ -0,0 +1,137 @@
+package dblist
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var wantFormatsConfig = &Config{
+	Version:  2,
+	Defaults: ConfigDefaults{Path: "/mnt/sheb", Days: 1},
+	Databases: []ConfigLine{
+		{Path: "/mnt/sheb", Filename: "buh_log8", Suffix: "-FULL.bak", Days: 7},
+		{Path: "/mnt/sheb", Filename: "buh_log8", Suffix: "-differ.bak", Days: 1},
+	},
+}
+
+func TestParseYAMLConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    *Config
+		wantErr string
+	}{
+		{name: "version 2",
+			config: `# backups of buh_log8
+version: 2
+defaults:
+  path: /mnt/sheb
+  days: 1
+databases:
+  - {Filename: buh_log8, Suffix: -FULL.bak, Days: 7} # weekly
+  - Filename: buh_log8
+    Suffix: -differ.bak
+`,
+			want: wantFormatsConfig,
+		},
+		{name: "version 1",
+			config: "- {path: g:/ShebB, Filename: buh_log8, Days: 1}\n",
+			want: &Config{Version: 1, Databases: []ConfigLine{
+				{Path: "g:/ShebB", Filename: "buh_log8", Days: 1},
+			}},
+		},
+		{name: "negative Days",
+			config:  "version: 2\ndatabases:\n  - Filename: a\n  - Filename: b\n    Days: -1\n",
+			wantErr: "c.yaml:4:5: config line b has negative Days -1",
+		},
+		{name: "unknown field",
+			config:  "- Filename: a\n  Dayz: 1\n",
+			wantErr: `c.yaml:1:3: json: unknown field "Dayz"`,
+		},
+		{name: "bad type",
+			config:  "- Filename: a\n  Days: many\n",
+			wantErr: "c.yaml:2:9: json: cannot unmarshal string",
+		},
+		{name: "syntax error",
+			config:  "- Filename: a\n Days: [\n",
+			wantErr: "c.yaml: config has bad yaml structure",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseYAMLConfig([]byte(tt.config), "c.yaml")
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Errorf("ParseYAMLConfig() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseYAMLConfig() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseYAMLConfig() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTOMLConfig(t *testing.T) {
+	got, err := ParseTOMLConfig([]byte(`# backups of buh_log8
+version = 2
+
+[defaults]
+path = "/mnt/sheb"
+days = 1
+
+[[databases]]
+Filename = "buh_log8"
+Suffix = "-FULL.bak"
+Days = 7 # weekly
+
+[[databases]]
+Filename = "buh_log8"
+Suffix = "-differ.bak"
+`), "c.toml")
+	if err != nil {
+		t.Fatalf("ParseTOMLConfig() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, wantFormatsConfig) {
+		t.Errorf("ParseTOMLConfig() = %+v, want %+v", got, wantFormatsConfig)
+	}
+
+	_, err = ParseTOMLConfig([]byte("version = 2\n[[databases]]\nFilename = \"a\"\nDays = -1\n"), "c.toml")
+	if err == nil || err.Error() != "c.toml: config line a has negative Days -1" {
+		t.Errorf("ParseTOMLConfig() error = %v", err)
+	}
+	_, err = ParseTOMLConfig([]byte("version = \n"), "c.toml")
+	if err == nil || !strings.HasPrefix(err.Error(), "c.toml: config has bad toml structure") {
+		t.Errorf("ParseTOMLConfig() error = %v", err)
+	}
+}
+
+func TestReadConfigFileFormats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dblist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{
+		"c.json": `[{"Filename": "buh_log8"}]`,
+		"c.yml":  "- Filename: buh_log8 # comment\n",
+		"c.TOML": "version = 2\n[[databases]]\nFilename = \"buh_log8\"\n",
+	} {
+		filename := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		conf, err := ReadConfig(filename)
+		if err != nil || len(conf) != 1 || conf[0].Filename != "buh_log8" {
+			t.Errorf("ReadConfig(%s) = %v, %v", name, conf, err)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package dblist
+
+import (
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// configReloadDelay collects several changes of a file, editors write files in several steps.
+const configReloadDelay = 100 * time.Millisecond
+
+// ConfigWatcher rereads a config file when it or its included files change.
+// Invalid edits are reported to Errors and the last good config is kept.
+// Only the latest update and the latest error are kept for a slow reader.
+type ConfigWatcher struct {
+	filename string
+	o        ConfigOverrides
+	w        dirWatcher
+	updates  chan []ConfigLine
+	errors   chan error
+	done     chan struct{}
+	stopped  chan struct{}
+	closing  sync.Once
+
+	mu      sync.Mutex
+	last    *Config
+	watched map[string][]string // directories to base names or patterns of config files in them
+}
+
+// WatchConfig reads a config file, see ReadConfigFileWith, and starts watching it.
+// An invalid config file is an error.
+func WatchConfig(filename string, o ConfigOverrides) (*ConfigWatcher, error) {
+	c, err := ReadConfigFileWith(filename, o)
+	if err != nil {
+		return nil, err
+	}
+	w, err := newDirWatcher()
+	if err != nil {
+		return nil, err
+	}
+	cw := &ConfigWatcher{
+		filename: filename,
+		o:        o,
+		w:        w,
+		updates:  make(chan []ConfigLine, 1),
+		errors:   make(chan error, 1),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+		last:     c,
+		watched:  make(map[string][]string),
+	}
+	if err := cw.watch(c); err != nil {
+		w.Close()
+		return nil, err
+	}
+	go cw.run()
+	return cw, nil
+}
+
+// Updates receives config lines of every new valid config.
+func (cw *ConfigWatcher) Updates() <-chan []ConfigLine {
+	return cw.updates
+}
+
+// Errors receives errors of reading config and watching it.
+func (cw *ConfigWatcher) Errors() <-chan error {
+	return cw.errors
+}
+
+// Config returns the last good config.
+func (cw *ConfigWatcher) Config() *Config {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	return cw.last
+}
+
+// Close stops watching, it may be called more than once.
+func (cw *ConfigWatcher) Close() error {
+	cw.closing.Do(func() { close(cw.done) })
+	err := cw.w.Close()
+	<-cw.stopped
+	return err
+}
+
+// watch adds directories of the config file and of its includes to the watcher.
+// Includes in directories with glob patterns are not watched.
+func (cw *ConfigWatcher) watch(c *Config) error {
+	files := []string{cw.filename}
+	for _, pattern := range c.Include {
+		pattern, err := cw.o.expand(pattern)
+		if err != nil {
+			return err
+		}
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(cw.filename), pattern)
+		}
+		if !IsGlobPath(filepath.Dir(pattern)) {
+			files = append(files, pattern)
+		}
+	}
+	watched := make(map[string][]string)
+	for _, f := range files {
+		dir := filepath.Clean(filepath.Dir(f))
+		if _, ok := watched[dir]; !ok {
+			cw.mu.Lock()
+			_, ok = cw.watched[dir]
+			cw.mu.Unlock()
+			if !ok {
+				if err := cw.w.Watch(dir); err != nil {
+					return err
+				}
+			}
+		}
+		watched[dir] = append(watched[dir], filepath.Base(f))
+	}
+	cw.mu.Lock()
+	for dir, names := range watched {
+		cw.watched[dir] = names // directories are never unwatched, their events are ignored
+	}
+	for dir := range cw.watched {
+		if _, ok := watched[dir]; !ok {
+			cw.watched[dir] = nil
+		}
+	}
+	cw.mu.Unlock()
+	return nil
+}
+
+// isConfigFile reports whether an event is about the config file or its includes.
+func (cw *ConfigWatcher) isConfigFile(ev fsEvent) bool {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	for _, pattern := range cw.watched[filepath.Clean(ev.Dir)] {
+		if ok, _ := filepath.Match(pattern, ev.Name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (cw *ConfigWatcher) run() {
+	defer close(cw.stopped)
+	timer := time.NewTimer(configReloadDelay)
+	timer.Stop()
+	events, errs := cw.w.Events(), cw.w.Errors()
+	for {
+		select {
+		case <-cw.done:
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			if cw.isConfigFile(ev) {
+				timer.Reset(configReloadDelay)
+			}
+		case err := <-errs:
+			cw.sendErr(err)
+			if err == ErrEventsOverflow {
+				timer.Reset(configReloadDelay)
+			}
+		case <-timer.C:
+			cw.reload()
+		}
+	}
+}
+
+func (cw *ConfigWatcher) reload() {
+	c, err := ReadConfigFileWith(cw.filename, cw.o)
+	if err != nil {
+		cw.sendErr(err)
+		return
+	}
+	if err := cw.watch(c); err != nil {
+		cw.sendErr(err)
+	}
+	cw.mu.Lock()
+	changed := !reflect.DeepEqual(c, cw.last)
+	if changed {
+		cw.last = c
+	}
+	cw.mu.Unlock()
+	if !changed {
+		return
+	}
+	lines := make([]ConfigLine, len(c.Databases))
+	copy(lines, c.Databases)
+	select {
+	case <-cw.updates: // drop the update nobody has read
+	default:
+	}
+	cw.updates <- lines
+}
+
+func (cw *ConfigWatcher) sendErr(err error) {
+	select {
+	case <-cw.errors: // drop the error nobody has read
+	default:
+	}
+	cw.errors <- err
+}
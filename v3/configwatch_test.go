@@ -0,0 +1,62 @@
+package dblist
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dblist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "dblist.json")
+	write := func(content string) {
+		// editors write a temporary file and rename it
+		if err := ioutil.WriteFile(filename+".tmp", []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(filename+".tmp", filename); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`[{"Filename":"a"}]`)
+
+	cw, err := WatchConfig(filename, ConfigOverrides{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cw.Close()
+
+	write(`[{"Filename":"a"}, {"Filename":"b"}]`)
+	select {
+	case lines := <-cw.Updates():
+		if len(lines) != 2 || lines[1].Filename != "b" {
+			t.Errorf("Updates() = %+v, want lines a and b", lines)
+		}
+	case err := <-cw.Errors():
+		t.Fatalf("Errors() = %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no update after config change")
+	}
+
+	write(`[{"Filename":"a", "Days":-1}]`)
+	select {
+	case lines := <-cw.Updates():
+		t.Errorf("Updates() = %+v after invalid edit", lines)
+	case err := <-cw.Errors():
+		if err == nil {
+			t.Error("Errors() = nil")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no error after invalid config change")
+	}
+	if n := len(cw.Config().Databases); n != 2 {
+		t.Errorf("Config() has %d lines, want the last good config with 2 lines", n)
+	}
+	cw.Close() // the deferred Close closes it again
+}
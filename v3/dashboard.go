@@ -0,0 +1,189 @@
+package dblist
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"time"
+)
+
+// dashboardTimelineDays is a period of time shown on timelines of a dashboard.
+const dashboardTimelineDays = 35
+
+// dashboard timeline picture size in pixels.
+const (
+	timelineWidth  = 700
+	timelineHeight = 16
+)
+
+// dashboardLine is a ConfigLine with its newest backup.
+type dashboardLine struct {
+	ConfigLine
+	Newest string
+	Age    string
+	Status string // css class: ok, late, stale or unknown
+}
+
+// dashboardMark is a backup on a timeline.
+type dashboardMark struct {
+	X     int
+	Full  bool
+	Title string
+}
+
+// dashboardTimeline holds backups of one database.
+type dashboardTimeline struct {
+	Path   string
+	DBName string
+	Marks  []dashboardMark
+}
+
+type dashboardData struct {
+	Generated string
+	Since     string
+	Width     int
+	Height    int
+	Lines     []dashboardLine
+	Timelines []dashboardTimeline
+	Uncovered []string
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Backups status</title>
+<style>
+body { font-family: sans-serif; font-size: 14px; margin: 1em 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 2px 8px; text-align: left; }
+td.num { text-align: right; }
+tr.ok td.age { background: #c8f0c8; }
+tr.late td.age { background: #f8e0a0; }
+tr.stale td.age { background: #f4b0b0; }
+tr.unknown td.age { background: #e0e0e0; }
+svg { background: #f4f4f4; }
+rect.full { fill: #2060c0; }
+rect.diff { fill: #80b0f0; }
+</style>
+</head>
+<body>
+<h1>Backups status</h1>
+<p>Generated {{.Generated}}.</p>
+<h2>Config lines</h2>
+<table>
+<tr><th>Database</th><th>Suffix</th><th>Path</th><th>Days</th><th>Newest backup</th><th>Age</th></tr>
+{{range .Lines}}<tr class="{{.Status}}"><td>{{.Filename}}</td><td>{{.Suffix}}</td><td>{{.Path}}</td><td class="num">{{.Days}}</td><td>{{.Newest}}</td><td class="age">{{.Age}}</td></tr>
+{{end}}</table>
+<h2>Timelines since {{.Since}}</h2>
+<table>
+{{range .Timelines}}<tr><td>{{.DBName}}</td><td>{{.Path}}</td><td><svg width="{{$.Width}}" height="{{$.Height}}">{{range .Marks}}<rect class="{{if .Full}}full{{else}}diff{{end}}" x="{{.X}}" y="0" width="3" height="{{$.Height}}"><title>{{.Title}}</title></rect>{{end}}</svg></td></tr>
+{{end}}</table>
+<h2>Files not covered by config</h2>
+{{if .Uncovered}}<ul>
+{{range .Uncovered}}<li>{{.}}</li>
+{{end}}</ul>{{else}}<p>None.</p>{{end}}
+</body>
+</html>
+`))
+
+// WriteDashboard writes a self-contained html page with status of backups.
+// Age of the newest backup of every config line is colored against its Days:
+// not older than Days is ok, not older than twice Days is late, others are stale.
+// now must be WallClock(time.Now()) or a time in the same form.
+func WriteDashboard(w io.Writer, conf []ConfigLine, filesByPath map[string][]FileInfoWin, now time.Time) error {
+	nameTosuffixes := GetMapFilenameToSuffixes(conf)
+	loc := confLocation(conf)
+	since := now.AddDate(0, 0, -dashboardTimelineDays)
+	data := dashboardData{
+		Generated: now.Format("2006-01-02 15:04"),
+		Since:     since.Format("2006-01-02"),
+		Width:     timelineWidth,
+		Height:    timelineHeight,
+		Lines:     []dashboardLine{},
+		Timelines: []dashboardTimeline{},
+		Uncovered: BuildReport(conf, filesByPath, 1).UncoveredFiles,
+	}
+
+	timelines := make(map[[2]string]*dashboardTimeline)
+	for _, stats := range EnrichConfigLines(conf, filesByPath) {
+		line := stats.ConfigLine
+		dl := dashboardLine{ConfigLine: line, Status: "unknown"}
+		if line.HasAnyFiles {
+			age := now.Sub(line.Modtime)
+			dl.Newest = line.Modtime.Format("2006-01-02 15:04")
+			dl.Age = formatAge(age)
+			dl.Status = ageStatus(age, line.Days)
+		} else if line.Days > 0 {
+			dl.Status = "stale"
+		}
+		data.Lines = append(data.Lines, dl)
+
+		for _, path := range PathsOfConfigLine(line, filesByPath) {
+			key := [2]string{path, line.Filename}
+			if _, ok := timelines[key]; !ok {
+				timelines[key] = &dashboardTimeline{Path: path, DBName: line.Filename}
+			}
+		}
+	}
+
+	for path, files := range filesByPath {
+		for _, f := range files {
+			dbname, suffix := GroupFunc(f.Name(), nameTosuffixes)
+			tl, ok := timelines[[2]string{path, dbname}] // a database matched by a pattern has its own timeline
+			if !ok || suffix == constFileNameHasWrongSuffix {
+				continue
+			}
+			t := BackupTimeIn(f, loc)
+			if t.Before(since) || t.After(now) {
+				continue
+			}
+			tl.Marks = append(tl.Marks, dashboardMark{
+				X:     int(float64(t.Sub(since)) / float64(now.Sub(since)) * (timelineWidth - 3)),
+				Full:  IsFullBackupSuffix(suffix),
+				Title: f.Name(),
+			})
+		}
+	}
+	for _, tl := range timelines {
+		sort.Slice(tl.Marks, func(i, j int) bool { return tl.Marks[i].X < tl.Marks[j].X })
+		data.Timelines = append(data.Timelines, *tl)
+	}
+	sort.Slice(data.Timelines, func(i, j int) bool {
+		if data.Timelines[i].DBName != data.Timelines[j].DBName {
+			return data.Timelines[i].DBName < data.Timelines[j].DBName
+		}
+		return data.Timelines[i].Path < data.Timelines[j].Path
+	})
+
+	return dashboardTemplate.Execute(w, data)
+}
+
+// ageStatus compares age of a backup with expected days between backups.
+func ageStatus(age time.Duration, days int) string {
+	if days <= 0 {
+		return "unknown"
+	}
+	period := time.Duration(days) * 24 * time.Hour
+	switch {
+	case age <= period:
+		return "ok"
+	case age <= 2*period:
+		return "late"
+	}
+	return "stale"
+}
+
+// formatAge formats a duration as days and hours, ex. 3d 4h.
+func formatAge(age time.Duration) string {
+	if age < 0 {
+		age = 0
+	}
+	days := int(age / (24 * time.Hour))
+	hours := int(age % (24 * time.Hour) / time.Hour)
+	if days == 0 {
+		return fmt.Sprintf("%dh", hours)
+	}
+	return fmt.Sprintf("%dd %dh", days, hours)
+}
@@ -0,0 +1,68 @@
+package dblist
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteDashboard(t *testing.T) {
+	conf := []ConfigLine{
+		{Path: "p", Filename: "<db>", Suffix: "-FULL.rar", Days: 7},
+		{Path: "p", Filename: "<db>", Suffix: "-differ.rar", Days: 1},
+		{Path: "p", Filename: "nobackups", Suffix: "-FULL.bak", Days: 1},
+		{Path: "p", Filename: `^tenant_\d+$`, Suffix: "-FULL.bak", Days: 1},
+	}
+	filesByPath := map[string][]FileInfoWin{
+		"p": files(
+			"<db>_2021-08-09T10-04-00-750-differ.rar",
+			"<db>_2021-08-06T17-47-01-147-FULL.rar",
+			"other_2021-08-06T17-47-01-147-FULL.bak",
+			"tenant_1_2021-08-10T21-00-05-001-FULL.bak",
+		),
+	}
+	now := mustparse("2021-08-11T12-00-00")
+
+	b := &bytes.Buffer{}
+	if err := WriteDashboard(b, conf, filesByPath, now); err != nil {
+		t.Fatal(err)
+	}
+	got := b.String()
+	for _, want := range []string{
+		`<tr class="ok"><td>&lt;db&gt;</td><td>-FULL.rar</td>`,
+		`<tr class="stale"><td>&lt;db&gt;</td><td>-differ.rar</td>`,
+		`<tr class="stale"><td>nobackups</td>`,
+		`<td class="age">4d 18h</td>`,
+		`<title>&lt;db&gt;_2021-08-09T10-04-00-750-differ.rar</title>`,
+		`<li>p/other_2021-08-06T17-47-01-147-FULL.bak</li>`,
+		`<tr><td>tenant_1</td><td>p</td><td><svg width="700" height="16"><rect class="full"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("WriteDashboard() has no %s", want)
+		}
+	}
+	for _, external := range []string{"src=", "href=", "http"} {
+		if strings.Contains(got, external) {
+			t.Errorf("WriteDashboard() refers to external assets with %s", external)
+		}
+	}
+}
+
+func TestAgeStatus(t *testing.T) {
+	tests := []struct {
+		age  time.Duration
+		days int
+		want string
+	}{
+		{time.Hour, 0, "unknown"},
+		{time.Hour, 1, "ok"},
+		{30 * time.Hour, 1, "late"},
+		{50 * time.Hour, 1, "stale"},
+	}
+	for _, tt := range tests {
+		if got := ageStatus(tt.age, tt.days); got != tt.want {
+			t.Errorf("ageStatus(%v, %d) = %v, want %v", tt.age, tt.days, got, tt.want)
+		}
+	}
+}
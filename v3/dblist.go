@@ -10,11 +10,9 @@
 package dblist
 
 import (
-	"encoding/json"
 	"errors"
-	"fmt"
-	"io/ioutil"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"time"
@@ -86,8 +84,10 @@ type ConfigLine struct {
 	Filename    string
 	Suffix      string
 	Days        int
+	Schedule    string // expected times of backups, see ParseSchedule
 	Modtime     time.Time
-	HasAnyFiles bool // indicating there were some files to choose from
+	HasAnyFiles bool           // indicating there were some files to choose from
+	Location    *time.Location `json:"-"` // time zone of times in file names, local if nil, config Timezone sets it
 }
 
 // FileInfoWin is a struct to hold os.FileInfo and additional windows attributes that we use.
@@ -97,40 +97,61 @@ type FileInfoWin struct {
 	WinAttr uint32
 }
 
+// IsUploaded reports whether a file has been marked as uploaded.
+// Files with A attribute (0x20) are considered not uploaded yet.
+func (fi FileInfoWin) IsUploaded() bool {
+	return fi.WinAttr&0x20 == 0
+}
+
 // GrouppingFunc is a function type that extracts database name from filename.
 // map[string][]string is used to hold a map of database names to slice of possible files suffixes.
 type GrouppingFunc func(string, map[string][]string) (string, string)
 
-// ReadConfig reads json config file.
+// ReadConfig reads json config file and returns its config lines, see ReadConfigFile.
 // ReadConfig doesn't sort config lines. User expected to sort the returned slice by himself.
 func ReadConfig(filename string) (datastruct []ConfigLine, err error) {
-
-	f, err := os.Open(filename)
+	c, err := ReadConfigFile(filename)
 	if err != nil {
-
-		return
-	}
-	b, err := ioutil.ReadAll(f)
-	if err != nil {
-
-		return
-	}
-	if b[0] == 0xEF || b[0] == 0xBB || b[1] == 0xBB {
-		b = b[3:] //skip BOM
+		return nil, err
 	}
+	return c.Databases, nil
+}
 
-	err = json.Unmarshal(b, &datastruct)
+// ReadNotifyConfig reads "notify" section of config file.
+// Version 1 config file has no notifiers.
+func ReadNotifyConfig(filename string) ([]NotifierConfig, error) {
+	c, err := ReadConfigFile(filename)
 	if err != nil {
-		err = fmt.Errorf("config has bad json structure: %w", err)
-		return datastruct, err
+		return nil, err
 	}
-	return datastruct, nil
+	return c.Notify, nil
+}
+
+// SortConfig sorts config lines ascending by database name and suffix.
+// Functions GetFilesNotCoveredByConfigFile and FindConfigLineByFilename expect such order.
+func SortConfig(configlines []ConfigLine) {
+	sort.Slice(configlines, func(i, j int) bool {
+		if configlines[i].Filename != configlines[j].Filename {
+			return configlines[i].Filename < configlines[j].Filename
+		}
+		return configlines[i].Suffix < configlines[j].Suffix
+	})
 }
 
 // GetUniquePaths returns unique paths from all available config lines.
+// Paths with glob patterns (ex. /backups/*/mssql) are expanded to existing directories.
 func GetUniquePaths(configstruct []ConfigLine) map[string]int {
 	retmap := make(map[string]int)
 	for _, str := range configstruct {
+		if IsGlobPath(str.Path) {
+			matches, _ := filepath.Glob(str.Path) // bad patterns are rejected by ReadConfig
+			for _, m := range matches {
+				if fi, err := os.Stat(m); err == nil && fi.IsDir() {
+					retmap[m] = 1
+				}
+			}
+			continue
+		}
 		if _, ok := retmap[str.Path]; !ok {
 			retmap[str.Path] = 1
 		}
@@ -140,6 +161,7 @@ func GetUniquePaths(configstruct []ConfigLine) map[string]int {
 
 // GetMapFilenameToSuffixes gets a map of database names to all its possible suffixes
 // according to a config json file.
+// Keys are config lines Filename, they may be database name patterns, see MatchDBName.
 func GetMapFilenameToSuffixes(configlines []ConfigLine) map[string][]string {
 	retmap := make(map[string][]string)
 	for _, v := range configlines {
@@ -269,7 +291,10 @@ func GroupFunc(source string, nameTosuffixes map[string][]string) (groupname, gr
 		return "", constFileNameHasWrongSuffix // not a database file name
 	}
 
-	suffix := nameTosuffixes[groupname]
+	var suffix []string
+	if name, ok := MatchDBName(groupname, nameTosuffixes); ok {
+		suffix = nameTosuffixes[name]
+	}
 	pos := -1
 	for _, sub := range suffix {
 
@@ -380,10 +405,11 @@ func GetFilesNotCoveredByConfigFile(filesindir []FileInfoWin, conf []ConfigLine,
 			continue
 		}
 		// try to find database name in config file
+		name, _ := MatchDBName(n1, nameTosuffixes)
 		pos := sort.Search(len(conf), func(i int) bool {
-			return conf[i].Filename >= n1
+			return conf[i].Filename >= name
 		})
-		if pos >= len(conf) || conf[pos].Filename != n1 {
+		if pos >= len(conf) || conf[pos].Filename != name {
 			// this database is not in config file
 			ret = append(ret, filestat)
 		}
@@ -391,6 +417,47 @@ func GetFilesNotCoveredByConfigFile(filesindir []FileInfoWin, conf []ConfigLine,
 	return ret
 }
 
+// IsGlobPath reports whether a config path is a glob pattern.
+func IsGlobPath(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// PathsOfConfigLine returns directories a config line refers to.
+// A path without glob pattern is returned as is, even if there is no such directory.
+// A glob pattern path is matched against keys of filesByPath, that is directories expanded by GetUniquePaths.
+func PathsOfConfigLine(line ConfigLine, filesByPath map[string][]FileInfoWin) []string {
+	if !IsGlobPath(line.Path) {
+		return []string{line.Path}
+	}
+	ret := []string{}
+	pattern := filepath.Clean(line.Path)
+	for path := range filesByPath {
+		if ok, _ := filepath.Match(pattern, filepath.Clean(path)); ok {
+			ret = append(ret, path)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// FilesOfConfigLine returns files that belong to the config line, that is
+// files in line.Path with line.Filename database name and line.Suffix suffix.
+func FilesOfConfigLine(line ConfigLine, filesByPath map[string][]FileInfoWin, nameTosuffixes map[string][]string) []FileInfoWin {
+	ret := []FileInfoWin{}
+	for _, path := range PathsOfConfigLine(line, filesByPath) {
+		for _, f := range filesByPath[path] {
+			dbname, suffix := GroupFunc(f.Name(), nameTosuffixes)
+			if suffix == constFileNameHasWrongSuffix || !strings.HasPrefix(suffix, line.Suffix) {
+				continue
+			}
+			if name, _ := MatchDBName(dbname, nameTosuffixes); name == line.Filename {
+				ret = append(ret, f)
+			}
+		}
+	}
+	return ret
+}
+
 // FindConfigLineByFilename finds a Config line that correcponds to a filename.
 // ConfigItems must be in ascending order.
 // One may need this for messages in errors.
@@ -401,13 +468,14 @@ func FindConfigLineByFilename(filename string, nameTosuffixes map[string][]strin
 	if dbname == "" && suffix == "" {
 		return nil
 	}
+	name, _ := MatchDBName(dbname, nameTosuffixes) // config lines may have a pattern instead of dbname
 	pos := sort.Search(lenconf, func(i int) bool {
 		// file name greater or if it's equal the suffix is greater or equal
-		return ConfigItems[i].Filename > dbname ||
-			ConfigItems[i].Filename == dbname && ConfigItems[i].Suffix >= suffix
+		return ConfigItems[i].Filename > name ||
+			ConfigItems[i].Filename == name && ConfigItems[i].Suffix >= suffix
 	})
 	if !(pos < lenconf &&
-		ConfigItems[pos].Filename == dbname && ConfigItems[pos].Suffix == suffix) {
+		ConfigItems[pos].Filename == name && ConfigItems[pos].Suffix == suffix) {
 		return nil // filename doesn't map to config at all
 	}
 	return &ConfigItems[pos]
@@ -352,3 +352,24 @@ func TestExtractDateTime(t *testing.T) {
 		})
 	}
 }
+
+func TestPathsOfConfigLine(t *testing.T) {
+	filesByPath := map[string][]FileInfoWin{
+		"/backups/srv1/mssql": files("db_2021-08-01T21-00-05-001-FULL.bak"),
+		"/backups/srv2/mssql": files("db_2021-08-02T21-00-05-001-FULL.bak"),
+		"/backups/srv2/pg":    files("db_2021-08-03T21-00-05-001-FULL.bak"),
+	}
+	line := ConfigLine{Path: "/backups/*/mssql", Filename: "db", Suffix: "-FULL.bak"}
+	got := PathsOfConfigLine(line, filesByPath)
+	want := []string{"/backups/srv1/mssql", "/backups/srv2/mssql"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PathsOfConfigLine() = %v, want %v", got, want)
+	}
+	if n := len(FilesOfConfigLine(line, filesByPath, GetMapFilenameToSuffixes([]ConfigLine{line}))); n != 2 {
+		t.Errorf("FilesOfConfigLine() returned %d files, want 2", n)
+	}
+	line.Path = "/backups/srv3/mssql"
+	if got := PathsOfConfigLine(line, filesByPath); !reflect.DeepEqual(got, []string{line.Path}) {
+		t.Errorf("PathsOfConfigLine() = %v, want the path as is", got)
+	}
+}
@@ -0,0 +1,86 @@
+package dblist
+
+import (
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// IsDBNamePattern reports whether a config line Filename is a pattern of database names.
+// A Filename starting with ^ is a regular expression, ex. ^tenant_\d+$.
+// A Filename with *, ? or [ is a glob pattern, ex. store*.
+func IsDBNamePattern(name string) bool {
+	return isDBNameRegexp(name) || strings.ContainsAny(name, "*?[")
+}
+
+func isDBNameRegexp(name string) bool {
+	return strings.HasPrefix(name, "^")
+}
+
+// ValidateDBNamePattern returns an error if a config line Filename is a bad pattern.
+func ValidateDBNamePattern(name string) error {
+	if isDBNameRegexp(name) {
+		_, err := dbNameRegexp(name)
+		return err
+	}
+	_, err := path.Match(name, "")
+	return err
+}
+
+// MatchDBName finds a key of nameTosuffixes that covers a database name.
+// Precedence of config lines Filename:
+// the exact database name;
+// glob patterns, then regular expressions;
+// a longer pattern of the same kind, then the lesser one.
+// Returns false if no config line covers dbname.
+func MatchDBName(dbname string, nameTosuffixes map[string][]string) (string, bool) {
+	if _, ok := nameTosuffixes[dbname]; ok {
+		return dbname, true
+	}
+	best, found := "", false
+	for name := range nameTosuffixes {
+		if !IsDBNamePattern(name) || !dbNameMatches(name, dbname) {
+			continue
+		}
+		if !found || dbNamePatternLess(name, best) {
+			best, found = name, true
+		}
+	}
+	return best, found
+}
+
+// dbNamePatternLess reports whether pattern a takes precedence over pattern b.
+func dbNamePatternLess(a, b string) bool {
+	if ra, rb := isDBNameRegexp(a), isDBNameRegexp(b); ra != rb {
+		return rb
+	}
+	if len(a) != len(b) {
+		return len(a) > len(b)
+	}
+	return a < b
+}
+
+func dbNameMatches(pattern, dbname string) bool {
+	if isDBNameRegexp(pattern) {
+		re, err := dbNameRegexp(pattern)
+		return err == nil && re.MatchString(dbname)
+	}
+	ok, _ := path.Match(pattern, dbname)
+	return ok
+}
+
+// dbNameRegexps caches compiled regular expressions, GroupFunc is called by sort.
+var dbNameRegexps sync.Map
+
+func dbNameRegexp(pattern string) (*regexp.Regexp, error) {
+	if re, ok := dbNameRegexps.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	dbNameRegexps.Store(pattern, re)
+	return re, nil
+}
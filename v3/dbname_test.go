@@ -0,0 +1,68 @@
+package dblist
+
+import (
+	"testing"
+)
+
+func TestMatchDBName(t *testing.T) {
+	conf := []ConfigLine{
+		{Filename: "store_main", Suffix: "-FULL.bak"},
+		{Filename: "store*", Suffix: "-FULL.bak"},
+		{Filename: "store_?", Suffix: "-FULL.bak"},
+		{Filename: `^tenant_\d+$`, Suffix: "-FULL.bak"},
+		{Filename: "^.*$", Suffix: ".rar"},
+	}
+	nameTosuffixes := GetMapFilenameToSuffixes(conf)
+	tests := []struct {
+		dbname string
+		want   string
+	}{
+		{"store_main", "store_main"},
+		{"store_1", "store_?"},
+		{"store_12", "store*"},
+		{"tenant_42", `^tenant_\d+$`},
+		{"tenant_x", "^.*$"},
+		{"zp", "^.*$"},
+	}
+	for _, tt := range tests {
+		if got, ok := MatchDBName(tt.dbname, nameTosuffixes); !ok || got != tt.want {
+			t.Errorf("MatchDBName(%s) = %s, %v, want %s", tt.dbname, got, ok, tt.want)
+		}
+	}
+	if got, ok := MatchDBName("buh", GetMapFilenameToSuffixes(conf[:4])); ok {
+		t.Errorf("MatchDBName(buh) = %s, want no match", got)
+	}
+}
+
+func TestGroupFuncDBNamePattern(t *testing.T) {
+	conf := []ConfigLine{
+		{Filename: "buh", Suffix: "-FULL.bak"},
+		{Filename: `^tenant_\d+$`, Suffix: "-FULL.bak"},
+		{Filename: `^tenant_\d+$`, Suffix: "-differ.bak"},
+	}
+	SortConfig(conf)
+	nameTosuffixes := GetMapFilenameToSuffixes(conf)
+	dir := files(
+		"tenant_1_2021-08-01T21-00-05-001-FULL.bak",
+		"tenant_1_2021-08-02T21-00-05-001-FULL.bak",
+		"tenant_2_2021-08-02T21-00-05-001-differ.bak",
+		"tenant_x_2021-08-02T21-00-05-001-FULL.bak",
+		"buh_2021-08-02T21-00-05-001-FULL.bak",
+	)
+	dbname, suffix := GroupFunc("tenant_2_2021-08-02T21-00-05-001-differ.bak", nameTosuffixes)
+	if dbname != "tenant_2" || suffix != "-differ.bak" {
+		t.Errorf("GroupFunc() = %s, %s, want tenant_2, -differ.bak", dbname, suffix)
+	}
+	uncovered := GetFilesNotCoveredByConfigFile(dir, conf, GroupFunc, nameTosuffixes)
+	if len(uncovered) != 1 || uncovered[0].Name() != "tenant_x_2021-08-02T21-00-05-001-FULL.bak" {
+		t.Errorf("GetFilesNotCoveredByConfigFile() = %v, want tenant_x file", uncovered)
+	}
+	last := GetLastFilesGroupedByFunc(dir, GroupFunc, nameTosuffixes, 1)
+	if len(last) != 3 {
+		t.Errorf("GetLastFilesGroupedByFunc() = %v, want newest files of buh, tenant_1 and tenant_2", last)
+	}
+	line := FindConfigLineByFilename("tenant_1_2021-08-01T21-00-05-001-FULL.bak", nameTosuffixes, conf)
+	if line == nil || line.Filename != `^tenant_\d+$` || line.Suffix != "-FULL.bak" {
+		t.Errorf("FindConfigLineByFilename() = %v, want tenant pattern line", line)
+	}
+}
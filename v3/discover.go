@@ -0,0 +1,113 @@
+package dblist
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Discover proposes config lines for backup files in a directory.
+// It is used to write a config file for a new server, see WriteConfigJSON.
+func Discover(dir string) ([]ConfigLine, error) {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	return DiscoverFiles(dir, ReadFilesFromPaths(map[string]int{dir: 1})[dir]), nil
+}
+
+// DiscoverFiles groups files by database name and a suffix after the time in file name
+// and returns a config line for every group.
+// Days of a config line is the median interval between backups of its group in days,
+// it is zero if a group has one file.
+// Config lines are sorted by database name and suffix.
+func DiscoverFiles(path string, files []FileInfoWin) []ConfigLine {
+	groups := make(map[[2]string][]time.Time)
+	for _, f := range files {
+		dbname, suffix := ExtractDBName(f.Name()), ExtractSuffix(f.Name())
+		if dbname == "" {
+			continue
+		}
+		key := [2]string{dbname, suffix}
+		groups[key] = append(groups[key], BackupTime(f))
+	}
+
+	ret := make([]ConfigLine, 0, len(groups))
+	for key, times := range groups {
+		ret = append(ret, ConfigLine{Path: path, Filename: key[0], Suffix: key[1], Days: suggestDays(times)})
+	}
+	SortConfig(ret)
+	return ret
+}
+
+// ExtractSuffix gets a suffix of a filename after the time in it.
+// ex. dbname_2021-08-10T10-04-00-717-differ.rar has suffix -differ.rar.
+// Digits separated by - T or _ right after the time are a part of the time.
+func ExtractSuffix(s string) string {
+	pos := Findpattern(s, timeInFilenamePattern)
+	if pos != -1 {
+		pos += len(timeInFilenamePattern)
+	} else if pos = Findpattern(s, YYYYminusMMpattern); pos != -1 {
+		pos += len(YYYYminusMMpattern)
+	} else {
+		return ""
+	}
+	rest := s[pos:]
+	for len(rest) > 1 && strings.IndexByte("-T_", rest[0]) != -1 && strings.IndexByte(constnumber, rest[1]) != -1 {
+		i := 1
+		for i < len(rest) && strings.IndexByte(constnumber, rest[i]) != -1 {
+			i++
+		}
+		rest = rest[i:]
+	}
+	return rest
+}
+
+// suggestDays returns the median interval between times rounded to days, at least 1 day.
+func suggestDays(times []time.Time) int {
+	if len(times) < 2 {
+		return 0
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	intervals := make([]time.Duration, 0, len(times)-1)
+	for i := 1; i < len(times); i++ {
+		intervals = append(intervals, times[i].Sub(times[i-1]))
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i] < intervals[j] })
+	median := intervals[len(intervals)/2]
+	days := int(math.Round(median.Hours() / 24))
+	if days < 1 {
+		days = 1
+	}
+	return days
+}
+
+// WriteConfigJSON writes config lines as a json array that ReadConfig reads.
+// Only Path, Filename, Suffix, Days and Schedule are written.
+func WriteConfigJSON(w io.Writer, conf []ConfigLine) error {
+	type jsonLine struct {
+		Path     string `json:"path"`
+		Filename string
+		Suffix   string
+		Days     int
+		Schedule string `json:",omitempty"`
+	}
+	lines := make([]jsonLine, 0, len(conf))
+	for _, line := range conf {
+		lines = append(lines, jsonLine{line.Path, line.Filename, line.Suffix, line.Days, line.Schedule})
+	}
+	b, err := json.MarshalIndent(lines, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,58 @@
+package dblist
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestExtractSuffix(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"buh_log8_2021-08-10T10-04-00-717-differ.rar", "-differ.rar"},
+		{"buh_log8_2021-08-10T10-04-00-FULL.bak", "-FULL.bak"},
+		{"buh_2021-08-10-FULL.bak", "-FULL.bak"},
+		{"buh_2021-08_1.7z", ".7z"},
+		{"buh_2021-08-10T10-04-00-717", ""},
+		{"readme.txt", ""},
+	}
+	for _, tt := range tests {
+		if got := ExtractSuffix(tt.name); got != tt.want {
+			t.Errorf("ExtractSuffix(%s) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDiscoverFiles(t *testing.T) {
+	got := DiscoverFiles("g:/ShebB", files(
+		"buh_log8_2021-08-01T21-00-05-001-FULL.bak",
+		"buh_log8_2021-08-08T21-00-07-001-FULL.bak",
+		"buh_log8_2021-08-15T21-03-05-001-FULL.bak",
+		"buh_log8_2021-08-09T21-00-05-001-differ.bak",
+		"buh_log8_2021-08-10T21-10-05-001-differ.bak",
+		"buh_log8_2021-08-11T20-55-05-001-differ.bak",
+		"zp_2021-08-11T20-55-05-001-FULL.bak",
+	))
+	want := []ConfigLine{
+		{Path: "g:/ShebB", Filename: "buh_log8", Suffix: "-FULL.bak", Days: 7},
+		{Path: "g:/ShebB", Filename: "buh_log8", Suffix: "-differ.bak", Days: 1},
+		{Path: "g:/ShebB", Filename: "zp", Suffix: "-FULL.bak", Days: 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiscoverFiles() = %+v, want %+v", got, want)
+	}
+
+	b := &bytes.Buffer{}
+	if err := WriteConfigJSON(b, got); err != nil {
+		t.Fatal(err)
+	}
+	c, err := ParseConfig(b.Bytes(), "discovered.json")
+	if err != nil {
+		t.Fatalf("ParseConfig() of WriteConfigJSON() output error = %v\n%s", err, b)
+	}
+	if !reflect.DeepEqual(c.Databases, want) {
+		t.Errorf("ParseConfig() = %+v, want %+v", c.Databases, want)
+	}
+}
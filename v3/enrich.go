@@ -0,0 +1,86 @@
+package dblist
+
+import (
+	"sort"
+	"time"
+)
+
+// ConfigLineStats is a config line with a summary of its files.
+// Embedded ConfigLine has Modtime set to the newest backup time and HasAnyFiles set.
+type ConfigLineStats struct {
+	ConfigLine
+	Pattern        string // database name pattern of the config line, Filename is a matched database then
+	Files          int    // number of files of the config line
+	TotalSize      int64  // size of files in bytes
+	NotUploaded    int    // number of files not marked as uploaded
+	NewestFilename string // base name of the newest file, empty if there are no files
+}
+
+// EnrichConfigLines summarizes files read by ReadFilesFromPaths for every config line.
+// conf is not modified, enriched copies are returned in the same order.
+// A config line with a database name pattern is summarized for every matched database ordered by name,
+// so a stale database is not hidden by newer backups of other databases.
+// Filename of such copies is a database name and Pattern is the pattern,
+// a pattern without matched databases has one copy with the pattern as Filename.
+func EnrichConfigLines(conf []ConfigLine, filesByPath map[string][]FileInfoWin) []ConfigLineStats {
+	nameTosuffixes := GetMapFilenameToSuffixes(conf)
+	ret := make([]ConfigLineStats, 0, len(conf))
+	for _, line := range conf {
+		lines, filesOf := splitByDBName(line, FilesOfConfigLine(line, filesByPath, nameTosuffixes), nameTosuffixes)
+		for i := range lines {
+			stats := configLineStats(lines[i], filesOf[i])
+			if IsDBNamePattern(line.Filename) {
+				stats.Pattern = line.Filename
+			}
+			ret = append(ret, stats)
+		}
+	}
+	return ret
+}
+
+// splitByDBName returns a config line with a pattern of database names as lines of every matched database
+// ordered by name, and files of every line. A line without pattern or without matched files is returned as is.
+func splitByDBName(line ConfigLine, files []FileInfoWin, nameTosuffixes map[string][]string) ([]ConfigLine, [][]FileInfoWin) {
+	if !IsDBNamePattern(line.Filename) || len(files) == 0 {
+		return []ConfigLine{line}, [][]FileInfoWin{files}
+	}
+	byDBName := make(map[string][]FileInfoWin)
+	for _, f := range files {
+		dbname, _ := GroupFunc(f.Name(), nameTosuffixes)
+		byDBName[dbname] = append(byDBName[dbname], f)
+	}
+	dbnames := make([]string, 0, len(byDBName))
+	for dbname := range byDBName {
+		dbnames = append(dbnames, dbname)
+	}
+	sort.Strings(dbnames)
+	lines := make([]ConfigLine, 0, len(dbnames))
+	filesOf := make([][]FileInfoWin, 0, len(dbnames))
+	for _, dbname := range dbnames {
+		matched := line
+		matched.Filename = dbname
+		lines = append(lines, matched)
+		filesOf = append(filesOf, byDBName[dbname])
+	}
+	return lines, filesOf
+}
+
+// configLineStats summarizes files of a config line.
+func configLineStats(line ConfigLine, files []FileInfoWin) ConfigLineStats {
+	ret := ConfigLineStats{ConfigLine: line}
+	ret.HasAnyFiles = len(files) != 0
+	ret.Modtime = time.Time{}
+	for _, f := range files {
+		ret.Files++
+		ret.TotalSize += f.Size()
+		if !f.IsUploaded() {
+			ret.NotUploaded++
+		}
+		t := BackupTimeIn(f, line.Location)
+		if t.After(ret.Modtime) || t.Equal(ret.Modtime) && f.Name() > ret.NewestFilename {
+			ret.Modtime = t
+			ret.NewestFilename = f.Name()
+		}
+	}
+	return ret
+}
@@ -0,0 +1,76 @@
+package dblist
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEnrichConfigLines(t *testing.T) {
+	conf := []ConfigLine{
+		{Path: "p", Filename: "db", Suffix: "-FULL.bak", Days: 7},
+		{Path: "p", Filename: "db", Suffix: "-differ.bak", Days: 1},
+		{Path: "other", Filename: "db", Suffix: "-FULL.bak", Days: 7},
+	}
+	filesByPath := map[string][]FileInfoWin{
+		"p": files(
+			"db_2021-08-01T21-00-05-001-FULL.bak",
+			"db_2021-08-08T21-00-05-001-FULL.bak",
+			"db_2021-08-09T21-00-05-001-differ.bak",
+			"db_2021-08-09T21-00-05-001-FULL.rar",
+		),
+	}
+	got := EnrichConfigLines(conf, filesByPath)
+	want := []ConfigLineStats{
+		{
+			ConfigLine:     ConfigLine{Path: "p", Filename: "db", Suffix: "-FULL.bak", Days: 7, HasAnyFiles: true, Modtime: mustparse("2021-08-08T21-00-05")},
+			Files:          2,
+			TotalSize:      2,
+			NotUploaded:    2,
+			NewestFilename: "db_2021-08-08T21-00-05-001-FULL.bak",
+		},
+		{
+			ConfigLine:     ConfigLine{Path: "p", Filename: "db", Suffix: "-differ.bak", Days: 1, HasAnyFiles: true, Modtime: mustparse("2021-08-09T21-00-05")},
+			Files:          1,
+			TotalSize:      1,
+			NotUploaded:    1,
+			NewestFilename: "db_2021-08-09T21-00-05-001-differ.bak",
+		},
+		{
+			ConfigLine: ConfigLine{Path: "other", Filename: "db", Suffix: "-FULL.bak", Days: 7},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("EnrichConfigLines() = %+v, want %+v", got, want)
+	}
+	if conf[0].HasAnyFiles {
+		t.Errorf("EnrichConfigLines() modified its argument")
+	}
+}
+
+func TestEnrichConfigLinesPattern(t *testing.T) {
+	conf := []ConfigLine{
+		{Path: "p", Filename: `^tenant_\d+$`, Suffix: "-FULL.bak", Days: 1},
+		{Path: "p", Filename: "store*", Suffix: "-FULL.bak", Days: 1},
+	}
+	filesByPath := map[string][]FileInfoWin{
+		"p": files(
+			"tenant_1_2021-08-09T21-00-05-001-FULL.bak",
+			"tenant_9_2020-12-20T21-00-05-001-FULL.bak",
+		),
+	}
+	got := EnrichConfigLines(conf, filesByPath)
+	want := []struct{ dbname, pattern, newest string }{
+		{"tenant_1", `^tenant_\d+$`, "tenant_1_2021-08-09T21-00-05-001-FULL.bak"},
+		{"tenant_9", `^tenant_\d+$`, "tenant_9_2020-12-20T21-00-05-001-FULL.bak"},
+		{"store*", "store*", ""},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("EnrichConfigLines() = %+v, want a copy for every database", got)
+	}
+	for i, w := range want {
+		if got[i].Filename != w.dbname || got[i].Pattern != w.pattern || got[i].NewestFilename != w.newest {
+			t.Errorf("EnrichConfigLines()[%d] = %s %s %s, want %s %s %s", i,
+				got[i].Filename, got[i].Pattern, got[i].NewestFilename, w.dbname, w.pattern, w.newest)
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package dblist
 
 import (
+	"fmt"
 	"io/ioutil"
 	"log"
+	"os"
 	"path/filepath"
 
 	"golang.org/x/sys/unix"
@@ -33,32 +35,49 @@ func ReadFilesFromPaths(uniquefolders map[string]int) map[string][]FileInfoWin {
 				continue // file name is not a DB backup file
 			}
 			// adds windows attributes to instance of special type FileInfoWin
-			fullFilename := filepath.Join(fullpath, v.Name())
+			fi, err := ReadFileInfoWin(filepath.Join(fullpath, v.Name()), v)
+			if err != nil {
+				log.Printf("%s", err)
+			}
+			retmap[uf] = append(retmap[uf], fi)
+		}
+	}
+	return retmap // map of slices of fileinfos
+}
 
-			var notuploaded uint32 = 0x20
+// ReadFileInfoWin adds 'uploaded' xattr of a file to its os.FileInfo.
+// A file without the attribute is not uploaded, it is returned even with an error of reading xattr.
+func ReadFileInfoWin(fullFilename string, fi os.FileInfo) (FileInfoWin, error) {
+	var notuploaded uint32 = 0x20
 
-			if sz, err := unix.Getxattr(fullFilename, constXattrUploaded, nil); err == nil {
+	if sz, err := unix.Getxattr(fullFilename, constXattrUploaded, nil); err == nil {
 
-				b := make([]byte, sz)
+		b := make([]byte, sz)
 
-				// under windows A attribute is set by default for new files.
-				// If a file has A attribute - we consider this file for uploading.
-				// under linux if there is NO 'Uploaded' attribute - we consider this file for uploading.
+		// under windows A attribute is set by default for new files.
+		// If a file has A attribute - we consider this file for uploading.
+		// under linux if there is NO 'Uploaded' attribute - we consider this file for uploading.
 
-				// there is an attribute 'Uploaded'
-				sz, err = unix.Getxattr(fullFilename, constXattrUploaded, b)
-				if len(b) != 0 {
-					notuploaded = 0x0
-				}
-			} else if err == unix.ENODATA {
-				// no attribute
-			} else {
-				// error reading attribute
-				log.Printf("can't get xattr for file %v, %v", fullFilename, err)
-			}
-
-			retmap[uf] = append(retmap[uf], FileInfoWin{FileInfo: v, WinAttr: notuploaded})
+		// there is an attribute 'Uploaded'
+		sz, err = unix.Getxattr(fullFilename, constXattrUploaded, b)
+		if len(b) != 0 {
+			notuploaded = 0x0
 		}
+	} else if err == unix.ENODATA {
+		// no attribute
+	} else {
+		// error reading attribute
+		return FileInfoWin{FileInfo: fi, WinAttr: notuploaded}, fmt.Errorf("can't get xattr for file %v, %v", fullFilename, err)
 	}
-	return retmap // map of slices of fileinfos
+	return FileInfoWin{FileInfo: fi, WinAttr: notuploaded}, nil
+}
+
+// MarkUploaded sets 'uploaded' xattr of a file.
+// Such file will not be considered for uploading by ReadFilesFromPaths.
+func MarkUploaded(fullFilename string) error {
+	err := unix.Setxattr(fullFilename, constXattrUploaded, []byte("1"), 0)
+	if err != nil {
+		return fmt.Errorf("can't set xattr for file %s: %w", fullFilename, err)
+	}
+	return nil
 }
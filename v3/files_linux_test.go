@@ -1,6 +1,9 @@
 package dblist
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -60,3 +63,27 @@ func compareMaps(want, got map[string][]FileInfoWin) bool {
 	}
 	return true
 }
+
+func TestMarkUploaded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dblist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := "db_2021-08-10T10-04-00-717-FULL.bak"
+	if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("backup"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got := ReadFilesFromPaths(map[string]int{dir: 1})
+	if len(got[dir]) != 1 || got[dir][0].IsUploaded() {
+		t.Fatalf("ReadFilesFromPaths() = %v, want one not uploaded file", got)
+	}
+	if err := MarkUploaded(filepath.Join(dir, name)); err != nil {
+		t.Fatalf("MarkUploaded() error = %v", err)
+	}
+	got = ReadFilesFromPaths(map[string]int{dir: 1})
+	if len(got[dir]) != 1 || !got[dir][0].IsUploaded() {
+		t.Errorf("ReadFilesFromPaths() = %v, want one uploaded file", got)
+	}
+}
@@ -1,8 +1,10 @@
 package dblist
 
 import (
+	"fmt"
 	"io/ioutil"
 	"log"
+	"os"
 	"path/filepath"
 
 	"golang.org/x/sys/windows"
@@ -34,16 +36,45 @@ func ReadFilesFromPaths(uniquefolders map[string]int) map[string][]FileInfoWin {
 			// under windows A attribute is set by default for new files.
 			// If a file has A attribute - we consider this file for uploading.
 
-			fullFilename := filepath.Join(k, v.Name())
-			uint16ptr, err1 := windows.UTF16PtrFromString(fullFilename)
-			WinAttr, err2 := windows.GetFileAttributes(uint16ptr)
-			if err1 != nil || err2 != nil {
+			fi, err := ReadFileInfoWin(filepath.Join(k, v.Name()), v)
+			if err != nil {
 				log.Printf("%s\r\n", err)
 				continue
 			}
 
-			retmap[k] = append(retmap[k], FileInfoWin{FileInfo: v, WinAttr: WinAttr})
+			retmap[k] = append(retmap[k], fi)
 		}
 	}
 	return retmap // map of slices of fileinfos
 }
+
+// ReadFileInfoWin adds windows attributes of a file to its os.FileInfo.
+func ReadFileInfoWin(fullFilename string, fi os.FileInfo) (FileInfoWin, error) {
+	uint16ptr, err := windows.UTF16PtrFromString(fullFilename)
+	if err != nil {
+		return FileInfoWin{}, err
+	}
+	WinAttr, err := windows.GetFileAttributes(uint16ptr)
+	if err != nil {
+		return FileInfoWin{}, fmt.Errorf("can't get attributes of file %s: %w", fullFilename, err)
+	}
+	return FileInfoWin{FileInfo: fi, WinAttr: WinAttr}, nil
+}
+
+// MarkUploaded clears A attribute of a file.
+// Such file will not be considered for uploading by ReadFilesFromPaths.
+func MarkUploaded(fullFilename string) error {
+	uint16ptr, err := windows.UTF16PtrFromString(fullFilename)
+	if err != nil {
+		return err
+	}
+	attr, err := windows.GetFileAttributes(uint16ptr)
+	if err != nil {
+		return fmt.Errorf("can't get attributes of file %s: %w", fullFilename, err)
+	}
+	err = windows.SetFileAttributes(uint16ptr, attr&^windows.FILE_ATTRIBUTE_ARCHIVE)
+	if err != nil {
+		return fmt.Errorf("can't set attributes of file %s: %w", fullFilename, err)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package dblist
+
+import "errors"
+
+// fsOp is a kind of a change in a watched directory.
+type fsOp int
+
+const (
+	fsCreate  fsOp = iota + 1 // a file was created
+	fsMovedIn                 // a complete file was moved into the directory
+	fsWrite                   // a file was closed after writing, on windows it stopped changing
+	fsRemove                  // a file was removed or moved out of the directory
+	fsAttrib                  // file attributes changed
+)
+
+// fsEvent is a change of a file in a watched directory.
+type fsEvent struct {
+	Dir  string
+	Name string
+	Op   fsOp
+}
+
+// ErrEventsOverflow means some changes were lost and directories must be read again.
+var ErrEventsOverflow = errors.New("file system events overflow")
+
+// ErrWatcherStopped means watching stopped by itself, ex. after an error of the operating system.
+var ErrWatcherStopped = errors.New("watcher stopped")
+
+// dirWatcher reports changes of files in directories.
+// newDirWatcher is implemented for every platform.
+type dirWatcher interface {
+	Watch(dir string) error
+	// Events is closed by Close.
+	Events() <-chan fsEvent
+	Errors() <-chan error
+	Close() error
+}
@@ -0,0 +1,137 @@
+package dblist
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const inotifyMask = unix.IN_CREATE | unix.IN_CLOSE_WRITE | unix.IN_MOVED_TO |
+	unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_ATTRIB | unix.IN_ONLYDIR
+
+// inotifyWatcher watches directories with linux inotify.
+type inotifyWatcher struct {
+	fd     int
+	f      *os.File // nonblocking fd, so Close interrupts Read
+	mu     sync.Mutex
+	dirs   map[int]string // watch descriptors to directories
+	events chan fsEvent
+	errors chan error
+	done   chan struct{}
+	closed sync.Once
+}
+
+func newDirWatcher() (dirWatcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify: %w", err)
+	}
+	w := &inotifyWatcher{
+		fd:     fd,
+		f:      os.NewFile(uintptr(fd), "inotify"),
+		dirs:   make(map[int]string),
+		events: make(chan fsEvent),
+		errors: make(chan error, 1),
+		done:   make(chan struct{}),
+	}
+	go w.read()
+	return w, nil
+}
+
+func (w *inotifyWatcher) Watch(dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	wd, err := unix.InotifyAddWatch(w.fd, abs, inotifyMask)
+	if err != nil {
+		return fmt.Errorf("can't watch %s: %w", dir, err)
+	}
+	w.mu.Lock()
+	w.dirs[wd] = dir
+	w.mu.Unlock()
+	return nil
+}
+
+func (w *inotifyWatcher) Events() <-chan fsEvent { return w.events }
+
+func (w *inotifyWatcher) Errors() <-chan error { return w.errors }
+
+func (w *inotifyWatcher) Close() error {
+	var err error
+	w.closed.Do(func() {
+		close(w.done)
+		err = w.f.Close()
+	})
+	return err
+}
+
+func (w *inotifyWatcher) read() {
+	defer close(w.events)
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := w.f.Read(buf)
+		if err != nil {
+			w.sendErr(err)
+			return
+		}
+		for off := 0; off+unix.SizeofInotifyEvent <= n; {
+			raw := (*unix.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			name := strings.TrimRight(string(buf[off+unix.SizeofInotifyEvent:off+unix.SizeofInotifyEvent+int(raw.Len)]), "\x00")
+			off += unix.SizeofInotifyEvent + int(raw.Len)
+
+			if raw.Mask&unix.IN_Q_OVERFLOW != 0 {
+				w.sendErr(ErrEventsOverflow)
+				continue
+			}
+			w.mu.Lock()
+			dir, ok := w.dirs[int(raw.Wd)]
+			if raw.Mask&unix.IN_IGNORED != 0 {
+				delete(w.dirs, int(raw.Wd)) // the directory was removed
+			}
+			w.mu.Unlock()
+			if !ok || name == "" {
+				continue
+			}
+
+			ev := fsEvent{Dir: dir, Name: name}
+			switch {
+			case raw.Mask&unix.IN_CREATE != 0:
+				ev.Op = fsCreate
+			case raw.Mask&unix.IN_MOVED_TO != 0:
+				ev.Op = fsMovedIn
+			case raw.Mask&unix.IN_CLOSE_WRITE != 0:
+				ev.Op = fsWrite
+			case raw.Mask&(unix.IN_DELETE|unix.IN_MOVED_FROM) != 0:
+				ev.Op = fsRemove
+			case raw.Mask&unix.IN_ATTRIB != 0:
+				ev.Op = fsAttrib
+			default:
+				continue
+			}
+			select {
+			case w.events <- ev:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
+
+// sendErr reports an error unless the watcher is closed.
+func (w *inotifyWatcher) sendErr(err error) {
+	select {
+	case <-w.done:
+		return
+	default:
+	}
+	select {
+	case w.errors <- err:
+	default: // the previous error is not read yet
+	}
+}
@@ -0,0 +1,140 @@
+package dblist
+
+import (
+	"io/ioutil"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// pollInterval is a period of directories reading on windows.
+const pollInterval = 2 * time.Second
+
+// pollFileState is what pollWatcher compares between readings of a directory.
+type pollFileState struct {
+	size    int64
+	modtime time.Time
+	attr    uint32
+	changed bool // the file was created or changed at the previous reading
+}
+
+// pollWatcher reads watched directories periodically and reports differences.
+// A file is written when it has not changed since the previous reading.
+type pollWatcher struct {
+	mu     sync.Mutex
+	dirs   map[string]map[string]pollFileState
+	events chan fsEvent
+	errors chan error
+	done   chan struct{}
+	closed sync.Once
+}
+
+func newDirWatcher() (dirWatcher, error) {
+	w := &pollWatcher{
+		dirs:   make(map[string]map[string]pollFileState),
+		events: make(chan fsEvent),
+		errors: make(chan error, 1),
+		done:   make(chan struct{}),
+	}
+	go w.poll()
+	return w, nil
+}
+
+func (w *pollWatcher) Watch(dir string) error {
+	state, err := readPollState(dir)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.dirs[dir] = state
+	w.mu.Unlock()
+	return nil
+}
+
+func (w *pollWatcher) Events() <-chan fsEvent { return w.events }
+
+func (w *pollWatcher) Errors() <-chan error { return w.errors }
+
+func (w *pollWatcher) Close() error {
+	w.closed.Do(func() { close(w.done) })
+	return nil
+}
+
+func readPollState(dir string) (map[string]pollFileState, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	state := make(map[string]pollFileState, len(infos))
+	for _, fi := range infos {
+		s := pollFileState{size: fi.Size(), modtime: fi.ModTime()}
+		if attr, ok := fi.Sys().(*syscall.Win32FileAttributeData); ok {
+			s.attr = attr.FileAttributes
+		}
+		state[fi.Name()] = s
+	}
+	return state, nil
+}
+
+func (w *pollWatcher) poll() {
+	defer close(w.events)
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+		w.mu.Lock()
+		dirs := make([]string, 0, len(w.dirs))
+		for dir := range w.dirs {
+			dirs = append(dirs, dir)
+		}
+		w.mu.Unlock()
+
+		for _, dir := range dirs {
+			state, err := readPollState(dir)
+			if err != nil {
+				select {
+				case w.errors <- err:
+				default:
+				}
+				continue
+			}
+			w.mu.Lock()
+			prev := w.dirs[dir]
+			w.dirs[dir] = state
+			w.mu.Unlock()
+
+			events := []fsEvent{}
+			for name, s := range state {
+				p, ok := prev[name]
+				switch {
+				case !ok:
+					events = append(events, fsEvent{Dir: dir, Name: name, Op: fsCreate})
+					s.changed = true
+				case p.size != s.size || !p.modtime.Equal(s.modtime):
+					s.changed = true
+				case p.changed:
+					events = append(events, fsEvent{Dir: dir, Name: name, Op: fsWrite})
+				case p.attr != s.attr:
+					events = append(events, fsEvent{Dir: dir, Name: name, Op: fsAttrib})
+				}
+				state[name] = s
+			}
+			for name := range prev {
+				if _, ok := state[name]; !ok {
+					events = append(events, fsEvent{Dir: dir, Name: name, Op: fsRemove})
+				}
+			}
+			for _, ev := range events {
+				select {
+				case w.events <- ev:
+				case <-w.done:
+					return
+				}
+			}
+		}
+	}
+}
@@ -1,5 +1,10 @@
 module github.com/zavla/dblist/v3
 
-require golang.org/x/sys v0.0.0-20190222072716-a9d3bda3a223
+require (
+	github.com/BurntSushi/toml v0.4.1
+	go.etcd.io/bbolt v1.3.5
+	golang.org/x/sys v0.0.0-20200202164722-d101bd2416d5
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 go 1.13
@@ -0,0 +1,21 @@
+module github.com/zavla/dblist/v3/grpcapi
+
+go 1.21
+
+require (
+	github.com/zavla/dblist/v3 v3.0.1-0.20261019061820-6d29e1e4d3d6
+	google.golang.org/grpc v1.66.2
+	google.golang.org/protobuf v1.34.2
+)
+
+require (
+	github.com/BurntSushi/toml v0.4.1 // indirect
+	golang.org/x/net v0.26.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+	golang.org/x/text v0.16.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240604185151-ef581f913117 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// the library of this repository in development, modules requiring grpcapi ignore replace
+replace github.com/zavla/dblist/v3 => ../
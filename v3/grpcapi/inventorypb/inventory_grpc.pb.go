@@ -0,0 +1,303 @@
+// Inventory of backup files in dblist config paths.
+// Go code is generated with protoc-gen-go and protoc-gen-go-grpc:
+// protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative inventory.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: inventory.proto
+
+package inventorypb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Inventory_ListGroups_FullMethodName   = "/dblist.inventory.v1.Inventory/ListGroups"
+	Inventory_ListFiles_FullMethodName    = "/dblist.inventory.v1.Inventory/ListFiles"
+	Inventory_LatestFiles_FullMethodName  = "/dblist.inventory.v1.Inventory/LatestFiles"
+	Inventory_RestoreChain_FullMethodName = "/dblist.inventory.v1.Inventory/RestoreChain"
+	Inventory_MarkUploaded_FullMethodName = "/dblist.inventory.v1.Inventory/MarkUploaded"
+)
+
+// InventoryClient is the client API for Inventory service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Inventory gives access to backup files in dblist config paths.
+// Config and files are read on every call.
+type InventoryClient interface {
+	// ListGroups streams groups of backup files: a database and a suffix in a directory.
+	ListGroups(ctx context.Context, in *ListGroupsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Group], error)
+	// ListFiles streams backup files.
+	ListFiles(ctx context.Context, in *ListFilesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[File], error)
+	// LatestFiles streams the newest files of every group of a database.
+	LatestFiles(ctx context.Context, in *LatestFilesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[File], error)
+	// RestoreChain returns files needed to restore a database: a FULL backup, the newest later differential backups and later log backups.
+	RestoreChain(ctx context.Context, in *RestoreChainRequest, opts ...grpc.CallOption) (*RestoreChainResponse, error)
+	// MarkUploaded marks backup files in config paths as uploaded.
+	MarkUploaded(ctx context.Context, in *MarkUploadedRequest, opts ...grpc.CallOption) (*MarkUploadedResponse, error)
+}
+
+type inventoryClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewInventoryClient(cc grpc.ClientConnInterface) InventoryClient {
+	return &inventoryClient{cc}
+}
+
+func (c *inventoryClient) ListGroups(ctx context.Context, in *ListGroupsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Group], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Inventory_ServiceDesc.Streams[0], Inventory_ListGroups_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListGroupsRequest, Group]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Inventory_ListGroupsClient = grpc.ServerStreamingClient[Group]
+
+func (c *inventoryClient) ListFiles(ctx context.Context, in *ListFilesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[File], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Inventory_ServiceDesc.Streams[1], Inventory_ListFiles_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListFilesRequest, File]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Inventory_ListFilesClient = grpc.ServerStreamingClient[File]
+
+func (c *inventoryClient) LatestFiles(ctx context.Context, in *LatestFilesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[File], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Inventory_ServiceDesc.Streams[2], Inventory_LatestFiles_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[LatestFilesRequest, File]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Inventory_LatestFilesClient = grpc.ServerStreamingClient[File]
+
+func (c *inventoryClient) RestoreChain(ctx context.Context, in *RestoreChainRequest, opts ...grpc.CallOption) (*RestoreChainResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RestoreChainResponse)
+	err := c.cc.Invoke(ctx, Inventory_RestoreChain_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *inventoryClient) MarkUploaded(ctx context.Context, in *MarkUploadedRequest, opts ...grpc.CallOption) (*MarkUploadedResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MarkUploadedResponse)
+	err := c.cc.Invoke(ctx, Inventory_MarkUploaded_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// InventoryServer is the server API for Inventory service.
+// All implementations must embed UnimplementedInventoryServer
+// for forward compatibility.
+//
+// Inventory gives access to backup files in dblist config paths.
+// Config and files are read on every call.
+type InventoryServer interface {
+	// ListGroups streams groups of backup files: a database and a suffix in a directory.
+	ListGroups(*ListGroupsRequest, grpc.ServerStreamingServer[Group]) error
+	// ListFiles streams backup files.
+	ListFiles(*ListFilesRequest, grpc.ServerStreamingServer[File]) error
+	// LatestFiles streams the newest files of every group of a database.
+	LatestFiles(*LatestFilesRequest, grpc.ServerStreamingServer[File]) error
+	// RestoreChain returns files needed to restore a database: a FULL backup, the newest later differential backups and later log backups.
+	RestoreChain(context.Context, *RestoreChainRequest) (*RestoreChainResponse, error)
+	// MarkUploaded marks backup files in config paths as uploaded.
+	MarkUploaded(context.Context, *MarkUploadedRequest) (*MarkUploadedResponse, error)
+	mustEmbedUnimplementedInventoryServer()
+}
+
+// UnimplementedInventoryServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedInventoryServer struct{}
+
+func (UnimplementedInventoryServer) ListGroups(*ListGroupsRequest, grpc.ServerStreamingServer[Group]) error {
+	return status.Errorf(codes.Unimplemented, "method ListGroups not implemented")
+}
+func (UnimplementedInventoryServer) ListFiles(*ListFilesRequest, grpc.ServerStreamingServer[File]) error {
+	return status.Errorf(codes.Unimplemented, "method ListFiles not implemented")
+}
+func (UnimplementedInventoryServer) LatestFiles(*LatestFilesRequest, grpc.ServerStreamingServer[File]) error {
+	return status.Errorf(codes.Unimplemented, "method LatestFiles not implemented")
+}
+func (UnimplementedInventoryServer) RestoreChain(context.Context, *RestoreChainRequest) (*RestoreChainResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreChain not implemented")
+}
+func (UnimplementedInventoryServer) MarkUploaded(context.Context, *MarkUploadedRequest) (*MarkUploadedResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MarkUploaded not implemented")
+}
+func (UnimplementedInventoryServer) mustEmbedUnimplementedInventoryServer() {}
+func (UnimplementedInventoryServer) testEmbeddedByValue()                   {}
+
+// UnsafeInventoryServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to InventoryServer will
+// result in compilation errors.
+type UnsafeInventoryServer interface {
+	mustEmbedUnimplementedInventoryServer()
+}
+
+func RegisterInventoryServer(s grpc.ServiceRegistrar, srv InventoryServer) {
+	// If the following call pancis, it indicates UnimplementedInventoryServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Inventory_ServiceDesc, srv)
+}
+
+func _Inventory_ListGroups_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListGroupsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(InventoryServer).ListGroups(m, &grpc.GenericServerStream[ListGroupsRequest, Group]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Inventory_ListGroupsServer = grpc.ServerStreamingServer[Group]
+
+func _Inventory_ListFiles_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListFilesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(InventoryServer).ListFiles(m, &grpc.GenericServerStream[ListFilesRequest, File]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Inventory_ListFilesServer = grpc.ServerStreamingServer[File]
+
+func _Inventory_LatestFiles_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(LatestFilesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(InventoryServer).LatestFiles(m, &grpc.GenericServerStream[LatestFilesRequest, File]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Inventory_LatestFilesServer = grpc.ServerStreamingServer[File]
+
+func _Inventory_RestoreChain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreChainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InventoryServer).RestoreChain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Inventory_RestoreChain_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InventoryServer).RestoreChain(ctx, req.(*RestoreChainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inventory_MarkUploaded_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MarkUploadedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InventoryServer).MarkUploaded(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Inventory_MarkUploaded_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InventoryServer).MarkUploaded(ctx, req.(*MarkUploadedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Inventory_ServiceDesc is the grpc.ServiceDesc for Inventory service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Inventory_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "dblist.inventory.v1.Inventory",
+	HandlerType: (*InventoryServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RestoreChain",
+			Handler:    _Inventory_RestoreChain_Handler,
+		},
+		{
+			MethodName: "MarkUploaded",
+			Handler:    _Inventory_MarkUploaded_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListGroups",
+			Handler:       _Inventory_ListGroups_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ListFiles",
+			Handler:       _Inventory_ListFiles_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "LatestFiles",
+			Handler:       _Inventory_LatestFiles_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "inventory.proto",
+}
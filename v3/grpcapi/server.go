@@ -0,0 +1,223 @@
+// Package grpcapi is a gRPC service of backup inventory, see inventorypb/inventory.proto.
+// It is a separate module, so users of dblist don't depend on gRPC.
+package grpcapi
+
+//go:generate protoc -I inventorypb --go_out=inventorypb --go_opt=paths=source_relative --go-grpc_out=inventorypb --go-grpc_opt=paths=source_relative inventorypb/inventory.proto
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/zavla/dblist/v3"
+	"github.com/zavla/dblist/v3/grpcapi/inventorypb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server implements inventorypb.InventoryServer.
+// It reads config and files in config paths on every call, like httpapi.Server.
+type Server struct {
+	inventorypb.UnimplementedInventoryServer
+	ConfigFile string
+	Overrides  dblist.ConfigOverrides
+}
+
+// New returns a server of a config file.
+func New(configfile string, o dblist.ConfigOverrides) *Server {
+	return &Server{ConfigFile: configfile, Overrides: o}
+}
+
+func (s *Server) scan() (*dblist.Inventory, error) {
+	inv, err := dblist.ReadInventory(s.ConfigFile, s.Overrides)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return inv, nil
+}
+
+// file converts dblist.Inventory.File to its message.
+func file(inv *dblist.Inventory, path string, f dblist.FileInfoWin) *inventorypb.File {
+	bf := inv.File(path, f)
+	return &inventorypb.File{
+		Path:       bf.Path,
+		Name:       bf.Name,
+		Dbname:     bf.DBName,
+		Suffix:     bf.Suffix,
+		Size:       bf.Size,
+		Modtime:    timestamppb.New(bf.Modtime),
+		BackupTime: timestamppb.New(bf.BackupTime),
+		Uploaded:   bf.Uploaded,
+	}
+}
+
+// dbFiles returns files of a database in a path, all files if dbname is empty.
+func dbFiles(inv *dblist.Inventory, path, dbname string) []dblist.FileInfoWin {
+	ret := []dblist.FileInfoWin{}
+	for _, f := range inv.FilesByPath[path] {
+		if dbname == "" || dblist.ExtractDBName(f.Name()) == dbname {
+			ret = append(ret, f)
+		}
+	}
+	return ret
+}
+
+// ListGroups streams groups ordered by path, database name and suffix.
+func (s *Server) ListGroups(req *inventorypb.ListGroupsRequest, stream inventorypb.Inventory_ListGroupsServer) error {
+	inv, err := s.scan()
+	if err != nil {
+		return err
+	}
+	for _, path := range inv.Paths {
+		groups := make(map[[2]string]*inventorypb.Group)
+		for _, f := range dbFiles(inv, path, req.GetDbname()) {
+			file := file(inv, path, f)
+			key := [2]string{file.Dbname, file.Suffix}
+			g, ok := groups[key]
+			if !ok {
+				g = &inventorypb.Group{Path: path, Dbname: file.Dbname, Suffix: file.Suffix}
+				groups[key] = g
+			}
+			g.Files++
+			g.TotalSize += file.Size
+			if g.Newest == nil || file.BackupTime.AsTime().After(g.Newest.BackupTime.AsTime()) {
+				g.Newest = file
+			}
+		}
+		keys := make([][2]string, 0, len(groups))
+		for key := range groups {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i][0] < keys[j][0] || keys[i][0] == keys[j][0] && keys[i][1] < keys[j][1]
+		})
+		for _, key := range keys {
+			if err := stream.Send(groups[key]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ListFiles streams files ordered by path and name.
+func (s *Server) ListFiles(req *inventorypb.ListFilesRequest, stream inventorypb.Inventory_ListFilesServer) error {
+	inv, err := s.scan()
+	if err != nil {
+		return err
+	}
+	for _, path := range inv.Paths {
+		if req.GetPath() != "" && path != req.GetPath() {
+			continue
+		}
+		for _, f := range dbFiles(inv, path, req.GetDbname()) {
+			if req.GetNotUploaded() && f.IsUploaded() {
+				continue
+			}
+			if err := stream.Send(file(inv, path, f)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// LatestFiles streams files selected by dblist.GetLastFilesGroupedByFunc in every path.
+func (s *Server) LatestFiles(req *inventorypb.LatestFilesRequest, stream inventorypb.Inventory_LatestFilesServer) error {
+	keep := uint(req.GetKeep())
+	if keep == 0 {
+		keep = 1
+	}
+	inv, err := s.scan()
+	if err != nil {
+		return err
+	}
+	for _, path := range inv.Paths {
+		for _, f := range dblist.GetLastFilesGroupedByFunc(dbFiles(inv, path, req.GetDbname()), dblist.GroupFunc, inv.NameTosuffixes, keep) {
+			if err := stream.Send(file(inv, path, f)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RestoreChain returns dblist.RestoreChainIn of a path or of the path with the newest FULL backup.
+// Its time is converted to the config time zone of times in file names, see dblist.Config.Location.
+func (s *Server) RestoreChain(ctx context.Context, req *inventorypb.RestoreChainRequest) (*inventorypb.RestoreChainResponse, error) {
+	if req.GetDbname() == "" {
+		return nil, status.Error(codes.InvalidArgument, "no dbname")
+	}
+	inv, err := s.scan()
+	if err != nil {
+		return nil, err
+	}
+	loc := inv.Config.Location()
+	var at time.Time // the latest state
+	if req.GetAt() != nil {
+		at = dblist.WallClockIn(req.GetAt().AsTime(), loc) // times in file names are wall clock
+	}
+	var best []dblist.FileInfoWin
+	bestPath := ""
+	for _, path := range inv.Paths {
+		if req.GetPath() != "" && path != req.GetPath() {
+			continue
+		}
+		chain, err := dblist.RestoreChainIn(inv.FilesByPath[path], req.GetDbname(), inv.NameTosuffixes, at, loc)
+		if errors.Is(err, dblist.ErrNoFullBackup) {
+			continue
+		}
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		if best == nil || dblist.BackupTimeIn(chain[0], loc).After(dblist.BackupTimeIn(best[0], loc)) {
+			best, bestPath = chain, path
+		}
+	}
+	if best == nil {
+		return nil, status.Errorf(codes.NotFound, "database %s: %v", req.GetDbname(), dblist.ErrNoFullBackup)
+	}
+	resp := &inventorypb.RestoreChainResponse{}
+	for _, f := range best {
+		resp.Files = append(resp.Files, file(inv, bestPath, f))
+	}
+	return resp, nil
+}
+
+// MarkUploaded marks files with dblist.MarkUploaded holding locks of their directories, see dblist.LockPath.
+// Files must be backup files in config paths, nothing is marked otherwise.
+func (s *Server) MarkUploaded(ctx context.Context, req *inventorypb.MarkUploadedRequest) (*inventorypb.MarkUploadedResponse, error) {
+	inv, err := s.scan()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool)
+	for _, path := range inv.Paths {
+		abs, _ := filepath.Abs(path)
+		for _, f := range inv.FilesByPath[path] {
+			known[filepath.Join(abs, f.Name())] = true
+		}
+	}
+	for _, name := range req.GetFiles() {
+		if abs, _ := filepath.Abs(name); !known[abs] {
+			return nil, status.Errorf(codes.InvalidArgument, "%s is not a backup file in config paths", name)
+		}
+	}
+	resp := &inventorypb.MarkUploadedResponse{}
+	for _, name := range req.GetFiles() {
+		lock, err := dblist.LockPath(filepath.Dir(name), dblist.LockOptions{Op: "grpcapi mark uploaded"})
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "%v, marked before the error: %v", err, resp.Marked)
+		}
+		err = dblist.MarkUploaded(name)
+		lock.Unlock()
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "%v, marked before the error: %v", err, resp.Marked)
+		}
+		resp.Marked = append(resp.Marked, name)
+	}
+	return resp, nil
+}
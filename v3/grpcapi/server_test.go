@@ -0,0 +1,163 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zavla/dblist/v3"
+	"github.com/zavla/dblist/v3/grpcapi/inventorypb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dblist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{
+		"db_2021-08-01T21-00-00-001-FULL.bak",
+		"db_2021-08-08T21-00-00-001-FULL.bak",
+		"db_2021-08-09T21-00-00-001-differ.bak",
+		"db_2021-08-10T21-00-00-001-differ.bak",
+		"other_2021-08-10T21-00-00-001-FULL.bak",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, _ := json.Marshal([]dblist.ConfigLine{
+		{Path: dir, Filename: "db", Suffix: "-FULL.bak", Days: 1},
+		{Path: dir, Filename: "db", Suffix: "-differ.bak", Days: 1},
+	})
+	configfile := filepath.Join(dir, "config.json")
+	if err := ioutil.WriteFile(configfile, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	inventorypb.RegisterInventoryServer(srv, New(configfile, dblist.ConfigOverrides{}))
+	go srv.Serve(lis)
+	defer srv.Stop()
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := inventorypb.NewInventoryClient(conn)
+	ctx := context.Background()
+
+	type receiver interface {
+		Recv() (*inventorypb.File, error)
+	}
+	names := func(stream receiver, err error) []string {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ret := []string{}
+		for {
+			f, err := stream.Recv()
+			if err == io.EOF {
+				return ret
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			ret = append(ret, f.Name)
+		}
+	}
+
+	got := names(client.ListFiles(ctx, &inventorypb.ListFilesRequest{Dbname: "other"}))
+	if want := []string{"other_2021-08-10T21-00-00-001-FULL.bak"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListFiles() = %v, want %v", got, want)
+	}
+	got = names(client.LatestFiles(ctx, &inventorypb.LatestFilesRequest{Dbname: "db"}))
+	if want := []string{"db_2021-08-10T21-00-00-001-differ.bak", "db_2021-08-08T21-00-00-001-FULL.bak"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("LatestFiles() = %v, want %v", got, want)
+	}
+
+	groups, err := client.ListGroups(ctx, &inventorypb.ListGroupsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotGroups := []string{}
+	for {
+		g, err := groups.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotGroups = append(gotGroups, g.Dbname+g.Suffix+" "+g.Newest.Name)
+	}
+	wantGroups := []string{
+		"db-FULL.bak db_2021-08-08T21-00-00-001-FULL.bak",
+		"db-differ.bak db_2021-08-10T21-00-00-001-differ.bak",
+		"other other_2021-08-10T21-00-00-001-FULL.bak",
+	}
+	if !reflect.DeepEqual(gotGroups, wantGroups) {
+		t.Errorf("ListGroups() = %v, want %v", gotGroups, wantGroups)
+	}
+
+	chain, err := client.RestoreChain(ctx, &inventorypb.RestoreChainRequest{Dbname: "db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chain.Files) != 2 || chain.Files[0].Name != "db_2021-08-08T21-00-00-001-FULL.bak" {
+		t.Errorf("RestoreChain() = %v", chain.Files)
+	}
+	if _, err := client.RestoreChain(ctx, &inventorypb.RestoreChainRequest{Dbname: "absent"}); status.Code(err) != codes.NotFound {
+		t.Errorf("RestoreChain() of absent database error = %v, want NotFound", err)
+	}
+
+	if _, err := client.MarkUploaded(ctx, &inventorypb.MarkUploadedRequest{Files: []string{configfile}}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("MarkUploaded() of a config file error = %v, want InvalidArgument", err)
+	}
+}
+
+func TestRestoreChainTimezone(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dblist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"db_2021-08-01T21-00-00-001-FULL.bak", "db_2021-08-08T21-00-00-001-FULL.bak"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	configfile := filepath.Join(dir, "config.json")
+	config := `{"version": 2, "defaults": {"path": "` + filepath.ToSlash(dir) + `", "days": 1, "timezone": "Europe/Moscow"},
+		"databases": [{"filename": "db", "suffix": "-FULL.bak"}]}`
+	if err := ioutil.WriteFile(configfile, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// 19:00 UTC is 22:00 in Moscow, after the FULL backup of 21:00 in file name
+	at := timestamppb.New(time.Date(2021, 8, 8, 19, 0, 0, 0, time.UTC))
+	chain, err := New(configfile, dblist.ConfigOverrides{}).RestoreChain(context.Background(),
+		&inventorypb.RestoreChainRequest{Dbname: "db", At: at})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chain.Files) != 1 || chain.Files[0].Name != "db_2021-08-08T21-00-00-001-FULL.bak" {
+		t.Errorf("RestoreChain() = %v, want db_2021-08-08T21-00-00-001-FULL.bak", chain.Files)
+	}
+}
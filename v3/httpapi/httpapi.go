@@ -0,0 +1,242 @@
+// Package httpapi serves inventory of backup files in dblist config paths as json over HTTP.
+//
+// Endpoints:
+//
+//	GET  /databases                  summary of every database, see dblist.DatabaseReport
+//	GET  /databases/{name}/backups   all backup files of a database
+//	GET  /databases/{name}/latest    the newest files of every group of a database, ?keep=N copies
+//	GET  /uncovered                  files not covered by config
+//	POST /prune?dry_run=true         files that prune deletes, they are deleted with dry_run=false
+//
+// Errors are json objects {"error": "message"}.
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/zavla/dblist/v3"
+)
+
+// File is a json representation of a backup file.
+type File = dblist.BackupFile
+
+// PruneResult is a response of POST /prune.
+type PruneResult struct {
+	DryRun bool     `json:"dry_run"`
+	Files  []File   `json:"files"`            // files to delete or deleted files
+	Failed []string `json:"failed,omitempty"` // errors of deleting files
+}
+
+// Server is http.Handler of the API.
+// It reads config and files in config paths on every request, like dblist.MetricsHandler.
+type Server struct {
+	ConfigFile string
+	Overrides  dblist.ConfigOverrides
+	Keep       uint // copies kept in every group by prune, 1 if zero
+	MaxPercent int  // limit of prune in a directory, dblist.DefaultMaxDeletePercent if zero
+	AllowPrune bool // POST /prune deletes files only if set, otherwise only dry_run=true is allowed
+
+	pruneMu sync.Mutex // one prune at a time
+}
+
+// New returns a read only server of a config file.
+func New(configfile string, o dblist.ConfigOverrides) *Server {
+	return &Server{ConfigFile: configfile, Overrides: o}
+}
+
+// errNotFound is reported with http.StatusNotFound.
+var errNotFound = errors.New("not found")
+
+// statusError is an error with http status.
+type statusError struct {
+	code  int
+	err   error
+	allow string // Allow header of http.StatusMethodNotAllowed
+}
+
+func (e *statusError) Error() string { return e.err.Error() }
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	parts := strings.Split(path, "/")
+	var v interface{}
+	var err error
+	switch {
+	case path == "databases":
+		if err = allow(r, "GET"); err == nil {
+			v, err = s.databases()
+		}
+	case len(parts) == 3 && parts[0] == "databases" && parts[2] == "backups":
+		if err = allow(r, "GET"); err == nil {
+			v, err = s.backups(parts[1])
+		}
+	case len(parts) == 3 && parts[0] == "databases" && parts[2] == "latest":
+		if err = allow(r, "GET"); err == nil {
+			v, err = s.latest(parts[1], r)
+		}
+	case path == "uncovered":
+		if err = allow(r, "GET"); err == nil {
+			v, err = s.uncovered()
+		}
+	case path == "prune":
+		if err = allow(r, "POST"); err == nil {
+			v, err = s.prune(r)
+		}
+	default:
+		err = errNotFound
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err != nil {
+		code := http.StatusInternalServerError
+		var se *statusError
+		switch {
+		case errors.As(err, &se):
+			code = se.code
+			if se.allow != "" {
+				w.Header().Set("Allow", se.allow)
+			}
+		case errors.Is(err, errNotFound):
+			code = http.StatusNotFound
+		}
+		w.WriteHeader(code)
+		v = struct {
+			Error string `json:"error"`
+		}{err.Error()}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func allow(r *http.Request, method string) error {
+	if r.Method == method || method == "GET" && r.Method == "HEAD" {
+		return nil
+	}
+	return &statusError{code: http.StatusMethodNotAllowed, err: fmt.Errorf("method %s is not allowed", r.Method), allow: method}
+}
+
+// dbFiles returns files of a database in every path, files is a selector of files of one path.
+// A database without files is not found unless it is in config.
+func dbFiles(inv *dblist.Inventory, dbname string, files func(all []dblist.FileInfoWin) []dblist.FileInfoWin) ([]File, error) {
+	ret := []File{}
+	for _, path := range inv.Paths {
+		selected := []dblist.FileInfoWin{}
+		for _, f := range inv.FilesByPath[path] {
+			if dblist.ExtractDBName(f.Name()) == dbname {
+				selected = append(selected, f)
+			}
+		}
+		for _, f := range files(selected) {
+			ret = append(ret, inv.File(path, f))
+		}
+	}
+	if len(ret) == 0 {
+		if _, ok := dblist.MatchDBName(dbname, inv.NameTosuffixes); !ok {
+			return nil, fmt.Errorf("database %s: %w", dbname, errNotFound)
+		}
+	}
+	return ret, nil
+}
+
+func (s *Server) databases() ([]dblist.DatabaseReport, error) {
+	inv, err := dblist.ReadInventory(s.ConfigFile, s.Overrides)
+	if err != nil {
+		return nil, err
+	}
+	return dblist.BuildReport(inv.Databases, inv.FilesByPath, s.keep()).Databases, nil
+}
+
+func (s *Server) backups(dbname string) ([]File, error) {
+	inv, err := dblist.ReadInventory(s.ConfigFile, s.Overrides)
+	if err != nil {
+		return nil, err
+	}
+	return dbFiles(inv, dbname, func(all []dblist.FileInfoWin) []dblist.FileInfoWin { return all })
+}
+
+func (s *Server) latest(dbname string, r *http.Request) ([]File, error) {
+	keep := uint64(1)
+	if v := r.URL.Query().Get("keep"); v != "" {
+		var err error
+		if keep, err = strconv.ParseUint(v, 10, 32); err != nil || keep == 0 {
+			return nil, &statusError{code: http.StatusBadRequest, err: fmt.Errorf("bad keep %q", v)}
+		}
+	}
+	inv, err := dblist.ReadInventory(s.ConfigFile, s.Overrides)
+	if err != nil {
+		return nil, err
+	}
+	return dbFiles(inv, dbname, func(all []dblist.FileInfoWin) []dblist.FileInfoWin {
+		return dblist.GetLastFilesGroupedByFunc(all, dblist.GroupFunc, inv.NameTosuffixes, uint(keep))
+	})
+}
+
+func (s *Server) uncovered() ([]File, error) {
+	inv, err := dblist.ReadInventory(s.ConfigFile, s.Overrides)
+	if err != nil {
+		return nil, err
+	}
+	ret := []File{}
+	for _, path := range inv.Paths {
+		for _, f := range dblist.GetFilesNotCoveredByConfigFile(inv.FilesByPath[path], inv.Databases, dblist.GroupFunc, inv.NameTosuffixes) {
+			ret = append(ret, inv.File(path, f))
+		}
+	}
+	return ret, nil
+}
+
+func (s *Server) keep() uint {
+	if s.Keep == 0 {
+		return 1
+	}
+	return s.Keep
+}
+
+// prune deletes files with dblist.Prune like 'dblist prune', dry_run is true if absent.
+// It deletes all planned files or nothing, the response is 409 Conflict if a deletion plan of any directory
+// is unsafe or a directory is locked by another tool.
+func (s *Server) prune(r *http.Request) (*PruneResult, error) {
+	dryRun := true
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			return nil, &statusError{code: http.StatusBadRequest, err: fmt.Errorf("bad dry_run %q", v)}
+		}
+	}
+	if !dryRun && !s.AllowPrune {
+		return nil, &statusError{code: http.StatusForbidden, err: errors.New("prune is not allowed, use dry_run=true")}
+	}
+
+	maxPercent := s.MaxPercent
+	if maxPercent == 0 {
+		maxPercent = dblist.DefaultMaxDeletePercent
+	}
+
+	s.pruneMu.Lock()
+	defer s.pruneMu.Unlock()
+	inv, err := dblist.ReadInventory(s.ConfigFile, s.Overrides)
+	if err != nil {
+		return nil, err
+	}
+	res, err := dblist.Prune(inv.FilesByPath, inv.NameTosuffixes, dblist.PruneOptions{Keep: s.keep(), MaxPercent: maxPercent,
+		DryRun: dryRun, Lock: dblist.LockOptions{Op: "httpapi prune"}})
+	if errors.Is(err, dblist.ErrUnsafeDeletion) || errors.Is(err, dblist.ErrLocked) ||
+		errors.Is(err, dblist.ErrLockTimeout) || errors.Is(err, dblist.ErrStaleLock) {
+		return nil, &statusError{code: http.StatusConflict, err: fmt.Errorf("prune aborted: %w", err)}
+	}
+	if err != nil {
+		return nil, err
+	}
+	ret := &PruneResult{DryRun: dryRun, Files: []File{}, Failed: res.Failed}
+	for _, f := range res.Files {
+		ret.Files = append(ret.Files, inv.File(f.Path, f.FileInfoWin))
+	}
+	return ret, nil
+}
@@ -0,0 +1,118 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zavla/dblist/v3"
+)
+
+func TestServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dblist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{
+		"db_2021-08-01T21-00-00-001-FULL.bak",
+		"db_2021-08-08T21-00-00-001-FULL.bak",
+		"db_2021-08-09T21-00-00-001-differ.bak",
+		"db_2021-08-10T21-00-00-001-differ.bak",
+		"other_2021-08-10T21-00-00-001-FULL.bak",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, _ := json.Marshal([]dblist.ConfigLine{
+		{Path: dir, Filename: "db", Suffix: "-FULL.bak", Days: 1},
+		{Path: dir, Filename: "db", Suffix: "-differ.bak", Days: 1},
+		{Path: dir, Filename: "empty", Suffix: "-FULL.bak", Days: 1},
+	})
+	configfile := filepath.Join(dir, "config.json")
+	if err := ioutil.WriteFile(configfile, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := New(configfile, dblist.ConfigOverrides{})
+	s.MaxPercent = 100
+
+	names := func(body string) string {
+		files := []File{}
+		if err := json.Unmarshal([]byte(body), &files); err != nil {
+			t.Fatalf("bad response %s: %v", body, err)
+		}
+		ret := []string{}
+		for _, f := range files {
+			ret = append(ret, f.Name)
+		}
+		return strings.Join(ret, " ")
+	}
+	tests := []struct {
+		method, url string
+		wantCode    int
+		want        string // file names of the response or its prefix
+	}{
+		{"GET", "/databases", 200, "["},
+		{"GET", "/databases/db/backups", 200, "db_2021-08-01T21-00-00-001-FULL.bak db_2021-08-08T21-00-00-001-FULL.bak " +
+			"db_2021-08-09T21-00-00-001-differ.bak db_2021-08-10T21-00-00-001-differ.bak"},
+		{"GET", "/databases/db/latest", 200, "db_2021-08-10T21-00-00-001-differ.bak db_2021-08-08T21-00-00-001-FULL.bak"},
+		{"GET", "/databases/db/latest?keep=x", 400, `{`},
+		{"GET", "/databases/empty/latest", 200, ""},
+		{"GET", "/databases/absent/backups", 404, `{`},
+		{"GET", "/uncovered", 200, "other_2021-08-10T21-00-00-001-FULL.bak"},
+		{"GET", "/prune", 405, `{`},
+		{"POST", "/prune?dry_run=false", 403, `{`},
+		{"GET", "/unknown", 404, `{`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.url, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.url, nil))
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d\n%s", rec.Code, tt.wantCode, rec.Body)
+			}
+			got := rec.Body.String()
+			if tt.wantCode == 200 && strings.HasPrefix(tt.url, "/databases/") || tt.url == "/uncovered" {
+				got = names(got)
+				if got != tt.want {
+					t.Errorf("files = %s, want %s", got, tt.want)
+				}
+			} else if !strings.HasPrefix(got, tt.want) {
+				t.Errorf("response = %s, want %s...", got, tt.want)
+			}
+		})
+	}
+
+	s.AllowPrune = true
+	// an unsafe plan aborts the whole prune
+	s.MaxPercent = 10
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("POST", "/prune?dry_run=false", nil))
+	if rec.Code != 409 || !strings.Contains(rec.Body.String(), "prune aborted") {
+		t.Errorf("unsafe prune = %d %s, want 409", rec.Code, rec.Body)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "db_2021-08-01T21-00-00-001-FULL.bak")); err != nil {
+		t.Errorf("unsafe prune deleted files: %v", err)
+	}
+	s.MaxPercent = 100
+
+	for _, dryRun := range []string{"true", "false"} {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest("POST", "/prune?dry_run="+dryRun, nil))
+		res := PruneResult{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil || rec.Code != 200 {
+			t.Fatalf("prune dry_run=%s = %d %s", dryRun, rec.Code, rec.Body)
+		}
+		if len(res.Files) != 2 || res.DryRun != (dryRun == "true") {
+			t.Errorf("prune dry_run=%s = %+v, want 2 files", dryRun, res)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "db_2021-08-01T21-00-00-001-FULL.bak")); !os.IsNotExist(err) {
+		t.Error("prune didn't delete files")
+	}
+}
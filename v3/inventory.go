@@ -0,0 +1,60 @@
+package dblist
+
+import (
+	"sort"
+	"time"
+)
+
+// Inventory holds config and files read from config paths, see ReadInventory.
+// It is a snapshot for one request of a service, like httpapi and grpcapi.
+type Inventory struct {
+	Config         *Config
+	Databases      []ConfigLine // sorted Config.Databases, see SortConfig
+	NameTosuffixes map[string][]string
+	FilesByPath    map[string][]FileInfoWin
+	Paths          []string // sorted keys of FilesByPath
+}
+
+// BackupFile describes a backup file of an Inventory.
+type BackupFile struct {
+	Path       string    `json:"path"`
+	Name       string    `json:"name"`
+	DBName     string    `json:"dbname"`
+	Suffix     string    `json:"suffix"`
+	Size       int64     `json:"size"`
+	Modtime    time.Time `json:"modtime"`
+	BackupTime time.Time `json:"backup_time"` // time in file name, see BackupTimeIn
+	Uploaded   bool      `json:"uploaded"`
+}
+
+// ReadInventory reads a config file and files in its paths.
+func ReadInventory(configfile string, o ConfigOverrides) (*Inventory, error) {
+	c, err := ReadConfigFileWith(configfile, o)
+	if err != nil {
+		return nil, err
+	}
+	conf := c.Databases
+	SortConfig(conf)
+	inv := &Inventory{
+		Config:         c,
+		Databases:      conf,
+		NameTosuffixes: GetMapFilenameToSuffixes(conf),
+		FilesByPath:    ReadFilesFromPaths(GetUniquePaths(conf)),
+	}
+	for path := range inv.FilesByPath {
+		inv.Paths = append(inv.Paths, path)
+	}
+	sort.Strings(inv.Paths)
+	return inv, nil
+}
+
+// File describes a file of a path, its backup time is in the config time zone.
+// A file of a database that is not in config gets its database name from ExtractDBName.
+func (inv *Inventory) File(path string, f FileInfoWin) BackupFile {
+	dbname, suffix := GroupFunc(f.Name(), inv.NameTosuffixes)
+	if dbname == "" {
+		dbname = ExtractDBName(f.Name())
+	}
+	return BackupFile{Path: path, Name: f.Name(), DBName: dbname, Suffix: suffix, Size: f.Size(),
+		Modtime: f.ModTime(), BackupTime: BackupTimeIn(f, inv.Config.Location()), Uploaded: f.IsUploaded()}
+}
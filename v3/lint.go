@@ -0,0 +1,116 @@
+package dblist
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Kinds of lint issues.
+const (
+	LintMissingPath  = "missing_path"  // a config path does not exist or a glob path matches nothing
+	LintNoFiles      = "no_files"      // a config line database has no files in its path
+	LintUnusedSuffix = "unused_suffix" // files of a config line database never have its suffix
+	LintShadowed     = "shadowed"      // files of a config line are taken by another config line
+	LintUnconfigured = "unconfigured"  // a database on disk is not in config
+)
+
+// LintIssue is a mismatch between config and files on disk.
+type LintIssue struct {
+	Kind    string `json:"kind"`
+	Path    string `json:"path"`
+	DBName  string `json:"dbname,omitempty"`
+	Suffix  string `json:"suffix,omitempty"`
+	Message string `json:"message"`
+}
+
+func (i LintIssue) String() string {
+	return i.Kind + ": " + i.Message
+}
+
+// LintConfig compares config lines with files read by ReadFilesFromPaths or ReadListing.
+// A path is missing if filesByPath has no such key.
+// Issues are ordered by config lines and then by paths of unconfigured databases.
+func LintConfig(conf []ConfigLine, filesByPath map[string][]FileInfoWin) []LintIssue {
+	nameTosuffixes := GetMapFilenameToSuffixes(conf)
+	ret := []LintIssue{}
+	for _, line := range conf {
+		name := line.Filename + line.Suffix
+		issue := func(kind, path, format string, a ...interface{}) {
+			ret = append(ret, LintIssue{Kind: kind, Path: path, DBName: line.Filename, Suffix: line.Suffix,
+				Message: fmt.Sprintf(format, a...)})
+		}
+
+		paths := []string{}
+		for _, path := range PathsOfConfigLine(line, filesByPath) {
+			if _, ok := filesByPath[path]; ok {
+				paths = append(paths, path)
+			}
+		}
+		if len(paths) == 0 {
+			if IsGlobPath(line.Path) {
+				issue(LintMissingPath, line.Path, "config line %s: no directories match %s", name, line.Path)
+			} else {
+				issue(LintMissingPath, line.Path, "config line %s: directory %s doesn't exist", name, line.Path)
+			}
+			continue
+		}
+		if len(FilesOfConfigLine(line, filesByPath, nameTosuffixes)) != 0 {
+			continue
+		}
+
+		// files of the config line database and the first of them with the config line suffix
+		dbfiles := 0
+		var withSuffix *FileInfoWin
+		for _, path := range paths {
+			for i, f := range filesByPath[path] {
+				dbname := ExtractDBName(f.Name())
+				if dbname != line.Filename && !(IsDBNamePattern(line.Filename) && dbNameMatches(line.Filename, dbname)) {
+					continue
+				}
+				dbfiles++
+				if withSuffix == nil && strings.Contains(f.Name()[len(dbname):], line.Suffix) {
+					withSuffix = &filesByPath[path][i]
+				}
+			}
+		}
+		switch {
+		case dbfiles == 0:
+			issue(LintNoFiles, line.Path, "config line %s matches no files in %s", name, line.Path)
+		case withSuffix == nil:
+			issue(LintUnusedSuffix, line.Path, "config line %s: %d files of %s never have suffix %s", name, dbfiles, line.Filename, line.Suffix)
+		default:
+			dbname, suffix := GroupFunc(withSuffix.Name(), nameTosuffixes)
+			other, _ := MatchDBName(dbname, nameTosuffixes)
+			issue(LintShadowed, line.Path, "config line %s is shadowed by config line %s%s, ex. file %s", name, other, suffix, withSuffix.Name())
+		}
+	}
+
+	paths := make([]string, 0, len(filesByPath))
+	for path := range filesByPath {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		counts := make(map[string]int)
+		for _, f := range filesByPath[path] {
+			dbname := ExtractDBName(f.Name())
+			if dbname == "" {
+				continue
+			}
+			if _, ok := MatchDBName(dbname, nameTosuffixes); !ok {
+				counts[dbname]++
+			}
+		}
+		dbnames := make([]string, 0, len(counts))
+		for dbname := range counts {
+			dbnames = append(dbnames, dbname)
+		}
+		sort.Strings(dbnames)
+		for _, dbname := range dbnames {
+			ret = append(ret, LintIssue{Kind: LintUnconfigured, Path: path, DBName: dbname,
+				Message: fmt.Sprintf("database %s has %d files in %s but no config line", dbname, counts[dbname], path)})
+		}
+	}
+	return ret
+}
@@ -0,0 +1,48 @@
+package dblist
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLintConfig(t *testing.T) {
+	conf := []ConfigLine{
+		{Path: "p", Filename: "buh", Suffix: ".bak"},
+		{Path: "p", Filename: "buh", Suffix: "-FULL.bak"},
+		{Path: "p", Filename: "buh", Suffix: "-differ.rar"},
+		{Path: "p", Filename: "zp", Suffix: "-FULL.bak"},
+		{Path: "p", Filename: "store*", Suffix: "-FULL.bak"},
+		{Path: "p", Filename: "^store_main$", Suffix: "-FULL.bak"},
+		{Path: "absent", Filename: "buh", Suffix: "-log.trn"},
+		{Path: "/srv/*/mssql", Filename: "buh", Suffix: "-log.bak"},
+	}
+	filesByPath := map[string][]FileInfoWin{
+		"p": files(
+			"buh_2021-08-01T21-00-05-001-FULL.bak",
+			"buh_2021-08-02T21-00-05-001-differ.bak",
+			"store_main_2021-08-02T21-00-05-001-FULL.bak",
+			"other_2021-08-02T21-00-05-001-FULL.bak",
+			"other_2021-08-03T21-00-05-001-FULL.bak",
+		),
+	}
+	got := LintConfig(conf, filesByPath)
+	want := []LintIssue{
+		{Kind: LintShadowed, Path: "p", DBName: "buh", Suffix: "-FULL.bak",
+			Message: "config line buh-FULL.bak is shadowed by config line buh.bak, ex. file buh_2021-08-01T21-00-05-001-FULL.bak"},
+		{Kind: LintUnusedSuffix, Path: "p", DBName: "buh", Suffix: "-differ.rar",
+			Message: "config line buh-differ.rar: 2 files of buh never have suffix -differ.rar"},
+		{Kind: LintNoFiles, Path: "p", DBName: "zp", Suffix: "-FULL.bak",
+			Message: "config line zp-FULL.bak matches no files in p"},
+		{Kind: LintShadowed, Path: "p", DBName: "^store_main$", Suffix: "-FULL.bak",
+			Message: "config line ^store_main$-FULL.bak is shadowed by config line store*-FULL.bak, ex. file store_main_2021-08-02T21-00-05-001-FULL.bak"},
+		{Kind: LintMissingPath, Path: "absent", DBName: "buh", Suffix: "-log.trn",
+			Message: "config line buh-log.trn: directory absent doesn't exist"},
+		{Kind: LintMissingPath, Path: "/srv/*/mssql", DBName: "buh", Suffix: "-log.bak",
+			Message: "config line buh-log.bak: no directories match /srv/*/mssql"},
+		{Kind: LintUnconfigured, Path: "p", DBName: "other",
+			Message: "database other has 2 files in p but no config line"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LintConfig() =\n%+v\nwant\n%+v", got, want)
+	}
+}
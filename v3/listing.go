@@ -0,0 +1,126 @@
+package dblist
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// listedFile is a file from a directory listing.
+type listedFile struct {
+	name    string
+	size    int64
+	modtime time.Time
+}
+
+func (f listedFile) Name() string       { return f.name }
+func (f listedFile) Size() int64        { return f.size }
+func (f listedFile) Mode() os.FileMode  { return 0644 }
+func (f listedFile) ModTime() time.Time { return f.modtime }
+func (f listedFile) IsDir() bool        { return false }
+func (f listedFile) Sys() interface{}   { return nil }
+
+// ReadListingFile reads a directory listing from a file, see ReadListing.
+func ReadListingFile(filename string) (map[string][]FileInfoWin, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ret, err := ReadListing(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return ret, nil
+}
+
+// ReadListing reads a snapshot of directories instead of actual files, ex. to check a config in CI.
+// A listing is either json output of 'dblist list -json'
+// or a text with a full file name on every line, optionally followed by a tab and a file size,
+// ex. output of find /backups -type f -printf '%p\t%s\n' or dir /s /b.
+// Backslashes in text listings are converted to slashes, so config paths must use slashes.
+// Listed files are not uploaded unless json says so. Only database backup files are returned.
+func ReadListing(r io.Reader) (map[string][]FileInfoWin, error) {
+	br := bufio.NewReader(r)
+	start, err := br.Peek(1)
+	for err == nil && bytes.IndexByte([]byte(" \t\r\n\xEF\xBB\xBF"), start[0]) != -1 {
+		br.ReadByte()
+		start, err = br.Peek(1)
+	}
+	if err == io.EOF {
+		return map[string][]FileInfoWin{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if start[0] == '[' {
+		return readJSONListing(br)
+	}
+	return readTextListing(br)
+}
+
+func readJSONListing(r io.Reader) (map[string][]FileInfoWin, error) {
+	entries := []struct {
+		Path     string    `json:"path"`
+		Name     string    `json:"name"`
+		Size     int64     `json:"size"`
+		Modtime  time.Time `json:"modtime"`
+		Uploaded bool      `json:"uploaded"`
+	}{}
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("bad json listing: %w", err)
+	}
+	ret := make(map[string][]FileInfoWin)
+	for _, e := range entries {
+		if ExtractDBName(e.Name) == "" {
+			continue
+		}
+		f := FileInfoWin{FileInfo: listedFile{name: e.Name, size: e.Size, modtime: e.Modtime}, WinAttr: 0x20}
+		if e.Uploaded {
+			f.WinAttr = 0
+		}
+		ret[e.Path] = append(ret[e.Path], f)
+	}
+	return ret, nil
+}
+
+func readTextListing(r io.Reader) (map[string][]FileInfoWin, error) {
+	ret := make(map[string][]FileInfoWin)
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f := listedFile{}
+		if i := strings.LastIndexByte(line, '\t'); i != -1 {
+			size, err := strconv.ParseInt(strings.TrimSpace(line[i+1:]), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: bad file size: %w", n, err)
+			}
+			f.size = size
+			line = line[:i]
+		}
+		line = strings.Replace(line, `\`, "/", -1)
+		slash := strings.LastIndexByte(line, '/')
+		if slash == -1 {
+			return nil, fmt.Errorf("line %d: %q is not a full file name", n, line)
+		}
+		dir := line[:slash]
+		if dir == "" || strings.HasSuffix(dir, ":") {
+			dir += "/" // root of a file system or a drive
+		}
+		f.name = line[slash+1:]
+		if ExtractDBName(f.name) == "" {
+			continue
+		}
+		ret[dir] = append(ret[dir], FileInfoWin{FileInfo: f, WinAttr: 0x20})
+	}
+	return ret, sc.Err()
+}
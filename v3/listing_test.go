@@ -0,0 +1,69 @@
+package dblist
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadListing(t *testing.T) {
+	tests := []struct {
+		name    string
+		listing string
+		want    map[string][]string // paths to file names
+		wantErr string
+	}{
+		{name: "find",
+			listing: "/mnt/sheb/buh_2021-08-01T21-00-05-001-FULL.bak\t1024\n/mnt/sheb/readme.txt\t10\n/mnt/zp/zp_2021-08-01T21-00-05-001-FULL.bak\t1\n",
+			want: map[string][]string{
+				"/mnt/sheb": {"buh_2021-08-01T21-00-05-001-FULL.bak"},
+				"/mnt/zp":   {"zp_2021-08-01T21-00-05-001-FULL.bak"},
+			},
+		},
+		{name: "dir /s /b",
+			listing: "\xEF\xBB\xBFg:\\ShebB\\buh_2021-08-01T21-00-05-001-FULL.bak\r\ng:\\zp_2021-08-01T21-00-05-001-FULL.bak\r\n",
+			want: map[string][]string{
+				"g:/ShebB": {"buh_2021-08-01T21-00-05-001-FULL.bak"},
+				"g:/":      {"zp_2021-08-01T21-00-05-001-FULL.bak"},
+			},
+		},
+		{name: "dblist list -json",
+			listing: `[{"path":"g:/ShebB","name":"buh_2021-08-01T21-00-05-001-FULL.bak","size":1,"uploaded":true}]`,
+			want:    map[string][]string{"g:/ShebB": {"buh_2021-08-01T21-00-05-001-FULL.bak"}},
+		},
+		{name: "empty", listing: " \n", want: map[string][]string{}},
+		{name: "bad size", listing: "/mnt/a_2021-08-01.bak\tbig\n", wantErr: "line 1: bad file size"},
+		{name: "no path", listing: "a_2021-08-01.bak\n", wantErr: "line 1: \"a_2021-08-01.bak\" is not a full file name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadListing(strings.NewReader(tt.listing))
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Errorf("ReadListing() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadListing() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("ReadListing() = %v, want %v", got, tt.want)
+			}
+			for path, names := range tt.want {
+				if len(got[path]) != len(names) {
+					t.Errorf("ReadListing()[%s] = %v, want %v", path, got[path], names)
+					continue
+				}
+				for i, name := range names {
+					if got[path][i].Name() != name {
+						t.Errorf("ReadListing()[%s][%d] = %s, want %s", path, i, got[path][i].Name(), name)
+					}
+				}
+			}
+		})
+	}
+	got, _ := ReadListing(strings.NewReader(tests[2].listing))
+	if f := got["g:/ShebB"][0]; !f.IsUploaded() || f.Size() != 1 {
+		t.Errorf("ReadListing() json file = %+v, want uploaded file of size 1", f)
+	}
+}
@@ -0,0 +1,194 @@
+package dblist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrLocked means a lock file is locked by another process.
+var ErrLocked = errors.New("locked by another process")
+
+// ErrLockTimeout means LockPath waited for a lock longer than its timeout.
+var ErrLockTimeout = errors.New("lock timeout")
+
+// ErrStaleLock means a lock is held but its holder stopped refreshing it,
+// the holder hangs or a network file system kept the lock of a crashed client.
+// The lock can't be broken, the holder must be killed or the file system lock released by an administrator.
+var ErrStaleLock = errors.New("stale lock")
+
+// LockFileName is a lock file that LockPath creates in a directory.
+const LockFileName = ".dblist.lock"
+
+// Defaults of LockOptions.
+const (
+	DefaultLockTimeout = 30 * time.Second
+	DefaultStaleAfter  = 5 * time.Minute
+)
+
+// lockPollInterval is how often LockPath tries to lock a locked file.
+const lockPollInterval = 100 * time.Millisecond
+
+// staleCheckDelay lets a new holder refresh a lock file that was not used for a long time.
+const staleCheckDelay = time.Second
+
+// LockOwner is written to a lock file by the holder of an exclusive lock.
+type LockOwner struct {
+	PID   int       `json:"pid"`
+	Host  string    `json:"host"`
+	Op    string    `json:"op,omitempty"`
+	Since time.Time `json:"since"`
+}
+
+func (o LockOwner) String() string {
+	return fmt.Sprintf("%s pid %d on %s since %s", o.Op, o.PID, o.Host, o.Since.Format(time.RFC3339))
+}
+
+// FileLock is a lock of a file, the operating system releases it when the process exits.
+type FileLock struct {
+	f         *os.File
+	exclusive bool // the lock file has the owner
+}
+
+// TryLockFile creates a lock file if it doesn't exist and locks it exclusively without waiting.
+// An error wraps ErrLocked if another process holds the lock.
+// The lock file contains LockOwner of the process while it holds the lock, the file is never removed.
+func TryLockFile(filename string) (*FileLock, error) {
+	return tryLockFile(filename, false, "")
+}
+
+func tryLockFile(filename string, shared bool, op string) (*FileLock, error) {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f, shared); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("can't lock %s: %w", filename, err)
+	}
+	if shared {
+		return &FileLock{f: f}, nil
+	}
+	owner := LockOwner{PID: os.Getpid(), Op: op, Since: time.Now()}
+	owner.Host, _ = os.Hostname()
+	if b, err := json.Marshal(owner); err == nil && f.Truncate(0) == nil {
+		f.WriteAt(append(b, '\n'), 0)
+	}
+	return &FileLock{f: f, exclusive: true}, nil
+}
+
+// Unlock releases the lock.
+func (l *FileLock) Unlock() error {
+	if l.exclusive {
+		l.f.Truncate(0)
+	}
+	err := unlockFile(l.f)
+	if errclose := l.f.Close(); err == nil {
+		err = errclose
+	}
+	return err
+}
+
+// ReadLockOwner reads the owner of an exclusive lock from a lock file.
+// It is an error if nobody holds the lock or holders of a shared lock do.
+func ReadLockOwner(filename string) (LockOwner, error) {
+	owner := LockOwner{}
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return owner, err
+	}
+	if err := json.Unmarshal(b, &owner); err != nil {
+		return owner, fmt.Errorf("%s has no lock owner: %w", filename, err)
+	}
+	return owner, nil
+}
+
+// LockOptions tune LockPath.
+type LockOptions struct {
+	// Shared locks are held by readers, ex. uploaders, exclusive locks by operations that change files:
+	// deletion, marking uploaded other than by uploaders and renaming.
+	Shared bool
+	// Timeout is how long to wait for a lock, DefaultLockTimeout if zero, no waiting if negative.
+	Timeout time.Duration
+	// StaleAfter is DefaultStaleAfter if zero.
+	// A holder refreshes modification time of a lock file, a lock not refreshed for StaleAfter is stale.
+	StaleAfter time.Duration
+	// Op is written to a lock file so other processes see who holds the lock, ex. prune.
+	Op string
+}
+
+// PathLock is a lock of a backup directory, see LockPath.
+type PathLock struct {
+	l       *FileLock
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// LockPath locks a directory of backup files, ex. ConfigLine.Path, for cooperating processes.
+// Every tool built on dblist takes an exclusive lock before it deletes, marks or renames files in a directory
+// and a shared lock while it reads files that must not disappear, so prune doesn't delete a file being uploaded.
+// An uploader marks files it uploaded under its shared lock, see remote.Uploader.
+// LockPath waits for a lock until o.Timeout, a timeout error wraps ErrLockTimeout and tells the holder.
+// A lock held by a holder that doesn't refresh it is reported at once with an error wrapping ErrStaleLock.
+// The lock file LockFileName is created in the directory, so the directory must be writable.
+func LockPath(path string, o LockOptions) (*PathLock, error) {
+	if o.Timeout == 0 {
+		o.Timeout = DefaultLockTimeout
+	}
+	if o.StaleAfter == 0 {
+		o.StaleAfter = DefaultStaleAfter
+	}
+	filename := filepath.Join(path, LockFileName)
+	start := time.Now()
+	deadline := start.Add(o.Timeout)
+	for {
+		l, err := tryLockFile(filename, o.Shared, o.Op)
+		if err == nil {
+			os.Chtimes(filename, time.Now(), time.Now())
+			pl := &PathLock{l: l, stop: make(chan struct{}), stopped: make(chan struct{})}
+			go pl.refresh(filename, o.StaleAfter/3)
+			return pl, nil
+		}
+		if !errors.Is(err, ErrLocked) {
+			return nil, err
+		}
+		holder := "another process"
+		if owner, err := ReadLockOwner(filename); err == nil {
+			holder = owner.String()
+		}
+		fi, err := os.Stat(filename)
+		if err == nil && time.Since(fi.ModTime()) > o.StaleAfter && time.Since(start) >= staleCheckDelay {
+			return nil, fmt.Errorf("%s: %w, held by %s, not refreshed since %s", filename, ErrStaleLock, holder, fi.ModTime().Format(time.RFC3339))
+		}
+		if !time.Now().Before(deadline) {
+			return nil, fmt.Errorf("%s: %w, held by %s", filename, ErrLockTimeout, holder)
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// refresh updates modification time of a lock file, so waiters know the holder is alive.
+func (pl *PathLock) refresh(filename string, every time.Duration) {
+	defer close(pl.stopped)
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-pl.stop:
+			return
+		case now := <-t.C:
+			os.Chtimes(filename, now, now)
+		}
+	}
+}
+
+// Unlock releases the lock.
+func (pl *PathLock) Unlock() error {
+	close(pl.stop)
+	<-pl.stopped
+	return pl.l.Unlock()
+}
@@ -0,0 +1,90 @@
+package dblist
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTryLockFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dblist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "dblist.lock")
+
+	l, err := TryLockFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owner, err := ReadLockOwner(filename); err != nil || owner.PID != os.Getpid() {
+		t.Errorf("ReadLockOwner() = %+v, %v, want pid %d", owner, err, os.Getpid())
+	}
+	if _, err := TryLockFile(filename); !errors.Is(err, ErrLocked) {
+		t.Errorf("TryLockFile() of a locked file error = %v, want %v", err, ErrLocked)
+	}
+	if err := l.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadLockOwner(filename); err == nil {
+		t.Error("ReadLockOwner() of an unlocked file has no error")
+	}
+	l, err = TryLockFile(filename)
+	if err != nil {
+		t.Fatalf("TryLockFile() after Unlock error = %v", err)
+	}
+	l.Unlock()
+}
+
+func TestLockPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dblist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	nowait := LockOptions{Timeout: -1}
+
+	// readers share a lock, a writer waits for them
+	r1, err := LockPath(dir, LockOptions{Shared: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := LockPath(dir, LockOptions{Shared: true, Timeout: -1})
+	if err != nil {
+		t.Fatalf("second shared LockPath() error = %v", err)
+	}
+	if _, err := LockPath(dir, nowait); !errors.Is(err, ErrLockTimeout) {
+		t.Errorf("exclusive LockPath() of a shared lock error = %v, want %v", err, ErrLockTimeout)
+	}
+	r1.Unlock()
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		r2.Unlock()
+	}()
+	w, err := LockPath(dir, LockOptions{Op: "prune", Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("LockPath() after readers error = %v", err)
+	}
+	_, err = LockPath(dir, LockOptions{Shared: true, Timeout: -1})
+	if !errors.Is(err, ErrLockTimeout) {
+		t.Errorf("shared LockPath() of an exclusive lock error = %v, want %v", err, ErrLockTimeout)
+	}
+	if owner, _ := ReadLockOwner(filepath.Join(dir, LockFileName)); owner.Op != "prune" {
+		t.Errorf("lock owner = %+v, want op prune", owner)
+	}
+
+	// the holder doesn't refresh the lock file
+	old := time.Now().Add(-time.Hour)
+	w.stop <- struct{}{}
+	if err := os.Chtimes(filepath.Join(dir, LockFileName), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LockPath(dir, LockOptions{StaleAfter: time.Minute, Timeout: 5 * time.Second}); !errors.Is(err, ErrStaleLock) {
+		t.Errorf("LockPath() of a stale lock error = %v, want %v", err, ErrStaleLock)
+	}
+	w.l.Unlock()
+}
@@ -0,0 +1,30 @@
+package dblist
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockRange is a byte range far beyond the end of a lock file,
+// LockFileEx denies other processes reading locked bytes and the pid must be readable.
+var lockRange = windows.Overlapped{OffsetHigh: 0x7fffffff}
+
+// lockFile locks a file with LockFileEx without waiting.
+func lockFile(f *os.File, shared bool) error {
+	flags := uint32(windows.LOCKFILE_FAIL_IMMEDIATELY)
+	if !shared {
+		flags |= windows.LOCKFILE_EXCLUSIVE_LOCK
+	}
+	ol := lockRange
+	err := windows.LockFileEx(windows.Handle(f.Fd()), flags, 0, 1, 0, &ol)
+	if err == windows.ERROR_LOCK_VIOLATION {
+		return ErrLocked
+	}
+	return err
+}
+
+func unlockFile(f *os.File) error {
+	ol := lockRange
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, &ol)
+}
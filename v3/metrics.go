@@ -0,0 +1,141 @@
+package dblist
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metricFamily is a gauge with its samples in prometheus text format.
+type metricFamily struct {
+	name    string
+	help    string
+	samples []string
+}
+
+func (m *metricFamily) add(labels string, value float64) {
+	m.samples = append(m.samples, m.name+labels+" "+strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+// WriteMetrics writes gauges of backups freshness in prometheus text exposition format.
+// Gauges of every config line are labeled with path, dbname and suffix.
+// A config line with a database name pattern has gauges of every matched database labeled with its name and the pattern,
+// see EnrichConfigLines.
+// A config line without files has no age gauge, its dblist_backup_files is 0.
+// now must be WallClock(time.Now()) or a time in the same form.
+func WriteMetrics(w io.Writer, conf []ConfigLine, filesByPath map[string][]FileInfoWin, now time.Time) error {
+	nameTosuffixes := GetMapFilenameToSuffixes(conf)
+	age := &metricFamily{name: "dblist_newest_backup_age_seconds", help: "Age of the newest backup file."}
+	days := &metricFamily{name: "dblist_config_days", help: "Days value of a config line."}
+	count := &metricFamily{name: "dblist_backup_files", help: "Number of backup files."}
+	size := &metricFamily{name: "dblist_backup_bytes", help: "Total size of backup files."}
+	notUploaded := &metricFamily{name: "dblist_backup_files_not_uploaded", help: "Number of backup files not marked as uploaded."}
+	uncovered := &metricFamily{name: "dblist_uncovered_files", help: "Number of files not covered by config."}
+
+	for _, stats := range EnrichConfigLines(conf, filesByPath) {
+		line := stats.ConfigLine
+		labels := fmt.Sprintf(`{path="%s",dbname="%s",suffix="%s"}`,
+			escapeLabel(line.Path), escapeLabel(line.Filename), escapeLabel(line.Suffix))
+		if stats.Pattern != "" {
+			labels = fmt.Sprintf(`{path="%s",dbname="%s",pattern="%s",suffix="%s"}`,
+				escapeLabel(line.Path), escapeLabel(line.Filename), escapeLabel(stats.Pattern), escapeLabel(line.Suffix))
+		}
+		if line.HasAnyFiles {
+			age.add(labels, now.Sub(line.Modtime).Seconds())
+		}
+		days.add(labels, float64(line.Days))
+		count.add(labels, float64(stats.Files))
+		size.add(labels, float64(stats.TotalSize))
+		notUploaded.add(labels, float64(stats.NotUploaded))
+	}
+
+	paths := make([]string, 0, len(filesByPath))
+	for path := range filesByPath {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		n := len(GetFilesNotCoveredByConfigFile(filesByPath[path], sortedConfig(conf), GroupFunc, nameTosuffixes))
+		uncovered.add(fmt.Sprintf(`{path="%s"}`, escapeLabel(path)), float64(n))
+	}
+
+	ew := &errWriter{w: w}
+	for _, m := range []*metricFamily{age, days, count, size, notUploaded, uncovered} {
+		ew.printf("# HELP %s %s\n# TYPE %s gauge\n", m.name, m.help, m.name)
+		for _, s := range m.samples {
+			ew.printf("%s\n", s)
+		}
+	}
+	return ew.err
+}
+
+// WriteMetricsFile writes metrics for prometheus node exporter textfile collector.
+// The file is replaced atomically, so the collector never reads a partial file.
+func WriteMetricsFile(filename string, conf []ConfigLine, filesByPath map[string][]FileInfoWin, now time.Time) error {
+	b := &bytes.Buffer{}
+	if err := WriteMetrics(b, conf, filesByPath, now); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(b.Bytes())
+	if errclose := tmp.Close(); err == nil {
+		err = errclose
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filename)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("can't write metrics file %s: %w", filename, err)
+	}
+	return nil
+}
+
+// MetricsHandler returns http.Handler for prometheus /metrics endpoint.
+// It reads config json file and files in config paths on every request.
+func MetricsHandler(configfile string, o ConfigOverrides) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := ReadConfigFileWith(configfile, o)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		b := &bytes.Buffer{}
+		err = WriteMetrics(b, c.Databases, ReadFilesFromPaths(GetUniquePaths(c.Databases)), c.WallClock(time.Now()))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write(b.Bytes())
+	})
+}
+
+// sortedConfig returns a sorted copy of config lines.
+func sortedConfig(conf []ConfigLine) []ConfigLine {
+	ret := make([]ConfigLine, len(conf))
+	copy(ret, conf)
+	SortConfig(ret)
+	return ret
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel escapes a prometheus label value.
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
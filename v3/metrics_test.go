@@ -0,0 +1,84 @@
+package dblist
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	conf := []ConfigLine{
+		{Path: "p", Filename: "зп_в_камин", Suffix: "-FULL.rar", Days: 7},
+		{Path: "p", Filename: "nobackups", Suffix: "-FULL.bak", Days: 1},
+		{Path: "p", Filename: `^tenant_\d+$`, Suffix: "-FULL.bak", Days: 1},
+	}
+	filesByPath := map[string][]FileInfoWin{
+		"p": files(
+			"зп_в_камин_2021-08-01T17-47-03-337-FULL.rar",
+			"зп_в_камин_2021-08-06T17-47-01-147-FULL.rar",
+			"other_2021-08-06T17-47-01-147-FULL.bak",
+			"tenant_1_2021-08-06T16-47-01-147-FULL.bak",
+			"tenant_9_2021-08-05T18-47-01-147-FULL.bak",
+		),
+	}
+	now := mustparse("2021-08-06T18-47-01")
+
+	b := &bytes.Buffer{}
+	if err := WriteMetrics(b, conf, filesByPath, now); err != nil {
+		t.Fatal(err)
+	}
+	got := b.String()
+	for _, want := range []string{
+		"# TYPE dblist_newest_backup_age_seconds gauge\n" +
+			`dblist_newest_backup_age_seconds{path="p",dbname="зп_в_камин",suffix="-FULL.rar"} 3600` + "\n",
+		`dblist_backup_files{path="p",dbname="зп_в_камин",suffix="-FULL.rar"} 2` + "\n",
+		`dblist_backup_files{path="p",dbname="nobackups",suffix="-FULL.bak"} 0` + "\n",
+		`dblist_backup_bytes{path="p",dbname="зп_в_камин",suffix="-FULL.rar"} 2` + "\n",
+		`dblist_backup_files_not_uploaded{path="p",dbname="зп_в_камин",suffix="-FULL.rar"} 2` + "\n",
+		`dblist_uncovered_files{path="p"} 1` + "\n",
+		`dblist_newest_backup_age_seconds{path="p",dbname="tenant_1",pattern="^tenant_\\d+$",suffix="-FULL.bak"} 7200` + "\n",
+		`dblist_newest_backup_age_seconds{path="p",dbname="tenant_9",pattern="^tenant_\\d+$",suffix="-FULL.bak"} 86400` + "\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("WriteMetrics() =\n%s\nhas no\n%s", got, want)
+		}
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dblist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	b, _ := json.Marshal([]ConfigLine{{Path: dir, Filename: "db", Suffix: "-FULL.bak", Days: 1}})
+	configfile := filepath.Join(dir, "config.json")
+	if err := ioutil.WriteFile(configfile, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	MetricsHandler(configfile, ConfigOverrides{}).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != 200 || !strings.Contains(rec.Body.String(), `dblist_backup_files{path="`+dir+`",dbname="db",suffix="-FULL.bak"} 0`) {
+		t.Errorf("MetricsHandler() = %d\n%s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	MetricsHandler(filepath.Join(dir, "absent.json"), ConfigOverrides{}).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != 500 {
+		t.Errorf("MetricsHandler() with absent config = %d, want 500", rec.Code)
+	}
+
+	textfile := filepath.Join(dir, "dblist.prom")
+	if err := WriteMetricsFile(textfile, []ConfigLine{}, nil, mustparse("2021-08-06T18-47-01")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(textfile); err != nil {
+		t.Errorf("WriteMetricsFile() didn't create a file: %v", err)
+	}
+}
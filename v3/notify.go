@@ -0,0 +1,262 @@
+package dblist
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kinds of events.
+const (
+	EventStale        = "stale"         // the newest backup of a config line is older than its Days
+	EventMissed       = "missed"        // a scheduled backup has no file
+	EventUncovered    = "uncovered"     // files not covered by config
+	EventVerifyFailed = "verify_failed" // verification found a problem
+	EventPruned       = "pruned"        // files were deleted
+)
+
+// Event is a notification about backup files.
+type Event struct {
+	Kind    string    `json:"kind"`
+	Time    time.Time `json:"time"`
+	Path    string    `json:"path,omitempty"`
+	DBName  string    `json:"dbname,omitempty"`
+	Suffix  string    `json:"suffix,omitempty"`
+	Message string    `json:"message"`
+	Files   []string  `json:"files,omitempty"`
+}
+
+func (e Event) String() string {
+	s := e.Kind + ": " + e.Message
+	if len(e.Files) != 0 {
+		s += "\n\t" + strings.Join(e.Files, "\n\t")
+	}
+	return s
+}
+
+// Notifier sends events somewhere.
+type Notifier interface {
+	Notify(events []Event) error
+}
+
+// NotifierConfig describes a notification sink in "notify" section of a config file.
+type NotifierConfig struct {
+	Type     string   // webhook, slack or smtp
+	URL      string   // webhook or slack url
+	Server   string   // smtp server host:port
+	From     string   // smtp sender
+	To       []string // smtp recipients
+	Username string   // smtp user, no authentication if empty
+	Password string   // smtp password
+	Subject  string   // smtp subject
+}
+
+// NewNotifier creates a Notifier from its config.
+func NewNotifier(c NotifierConfig) (Notifier, error) {
+	switch c.Type {
+	case "webhook":
+		if c.URL == "" {
+			return nil, fmt.Errorf("webhook notifier has no URL")
+		}
+		return &WebhookNotifier{URL: c.URL}, nil
+	case "slack", "mattermost":
+		if c.URL == "" {
+			return nil, fmt.Errorf("slack notifier has no URL")
+		}
+		return &SlackNotifier{URL: c.URL}, nil
+	case "smtp":
+		if c.Server == "" || c.From == "" || len(c.To) == 0 {
+			return nil, fmt.Errorf("smtp notifier needs Server, From and To")
+		}
+		n := &SMTPNotifier{Server: c.Server, From: c.From, To: c.To, Subject: c.Subject}
+		if c.Username != "" {
+			host, _, err := net.SplitHostPort(c.Server)
+			if err != nil {
+				return nil, fmt.Errorf("smtp notifier has bad Server: %w", err)
+			}
+			n.Auth = smtp.PlainAuth("", c.Username, c.Password, host)
+		}
+		return n, nil
+	}
+	return nil, fmt.Errorf("unknown notifier type %q", c.Type)
+}
+
+// NewNotifiers creates one Notifier that sends events to all configured sinks.
+func NewNotifiers(configs []NotifierConfig) (Notifier, error) {
+	ret := MultiNotifier{}
+	for i, c := range configs {
+		n, err := NewNotifier(c)
+		if err != nil {
+			return nil, fmt.Errorf("notifier %d: %w", i, err)
+		}
+		ret = append(ret, n)
+	}
+	return ret, nil
+}
+
+// MultiNotifier sends events to every its notifier.
+type MultiNotifier []Notifier
+
+// Notify sends events to all notifiers, even if some of them fail.
+func (m MultiNotifier) Notify(events []Event) error {
+	errs := []string{}
+	for _, n := range m {
+		if err := n.Notify(events); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("notification failed: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// WebhookNotifier posts events as json array.
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client // http.DefaultClient if nil
+}
+
+// Notify posts events to the webhook.
+func (n *WebhookNotifier) Notify(events []Event) error {
+	b, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	return postJSON(n.Client, n.URL, b)
+}
+
+// SlackNotifier posts events as a text message to Slack or Mattermost incoming webhook.
+type SlackNotifier struct {
+	URL    string
+	Client *http.Client // http.DefaultClient if nil
+}
+
+// Notify posts events as one message.
+func (n *SlackNotifier) Notify(events []Event) error {
+	b, err := json.Marshal(struct {
+		Text string `json:"text"`
+	}{eventsText(events)})
+	if err != nil {
+		return err
+	}
+	return postJSON(n.Client, n.URL, b)
+}
+
+func postJSON(client *http.Client, url string, b []byte) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s responded with %s", url, resp.Status)
+	}
+	return nil
+}
+
+// SMTPNotifier sends events by email.
+type SMTPNotifier struct {
+	Server  string // host:port
+	Auth    smtp.Auth
+	From    string
+	To      []string
+	Subject string // "dblist notification" if empty
+}
+
+// Notify sends one email with all events.
+func (n *SMTPNotifier) Notify(events []Event) error {
+	subject := n.Subject
+	if subject == "" {
+		subject = "dblist notification"
+	}
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "From: %s\r\n", n.From)
+	fmt.Fprintf(b, "To: %s\r\n", strings.Join(n.To, ", "))
+	fmt.Fprintf(b, "Subject: %s\r\n", subject)
+	fmt.Fprintf(b, "MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.Replace(eventsText(events), "\n", "\r\n", -1))
+	b.WriteString("\r\n")
+	return smtp.SendMail(n.Server, n.Auth, n.From, n.To, b.Bytes())
+}
+
+// eventsText formats events for humans.
+func eventsText(events []Event) string {
+	lines := make([]string, 0, len(events))
+	for _, e := range events {
+		lines = append(lines, e.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// StaleEvents returns events for config lines that have no backup files
+// or whose newest backup is older than Days. Lines with zero Days are not checked.
+// now must be WallClock(time.Now()) or a time in the same form.
+func StaleEvents(conf []ConfigLine, filesByPath map[string][]FileInfoWin, now time.Time) []Event {
+	ret := []Event{}
+	for _, stats := range EnrichConfigLines(conf, filesByPath) {
+		line := stats.ConfigLine
+		if line.Days <= 0 {
+			continue
+		}
+		e := Event{Kind: EventStale, Time: now, Path: line.Path, DBName: line.Filename, Suffix: line.Suffix}
+		switch {
+		case !line.HasAnyFiles:
+			e.Message = fmt.Sprintf("%s%s has no backups in %s", line.Filename, line.Suffix, line.Path)
+		case ageStatus(now.Sub(line.Modtime), line.Days) != "ok":
+			e.Message = fmt.Sprintf("%s%s newest backup %s is %s old", line.Filename, line.Suffix,
+				stats.NewestFilename, formatAge(now.Sub(line.Modtime)))
+		default:
+			continue
+		}
+		ret = append(ret, e)
+	}
+	return ret
+}
+
+// MissedEvents converts missed backups to events.
+func MissedEvents(missed []MissedBackup, now time.Time) []Event {
+	ret := make([]Event, 0, len(missed))
+	for _, m := range missed {
+		ret = append(ret, Event{Kind: EventMissed, Time: now,
+			Path: m.Line.Path, DBName: m.Line.Filename, Suffix: m.Line.Suffix, Message: m.String()})
+	}
+	return ret
+}
+
+// UncoveredEvents returns an event for every path with files not covered by config.
+func UncoveredEvents(conf []ConfigLine, filesByPath map[string][]FileInfoWin, now time.Time) []Event {
+	nameTosuffixes := GetMapFilenameToSuffixes(conf)
+	sorted := sortedConfig(conf)
+	paths := make([]string, 0, len(filesByPath))
+	for path := range filesByPath {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	ret := []Event{}
+	for _, path := range paths {
+		uncovered := GetFilesNotCoveredByConfigFile(filesByPath[path], sorted, GroupFunc, nameTosuffixes)
+		if len(uncovered) == 0 {
+			continue
+		}
+		names := make([]string, 0, len(uncovered))
+		for _, f := range uncovered {
+			names = append(names, f.Name())
+		}
+		sort.Strings(names)
+		ret = append(ret, Event{Kind: EventUncovered, Time: now, Path: path,
+			Message: fmt.Sprintf("%d files in %s are not covered by config", len(names), path),
+			Files:   names})
+	}
+	return ret
+}
@@ -0,0 +1,190 @@
+package dblist
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testEvents = []Event{
+	{Kind: EventStale, Path: "p", DBName: "db", Suffix: "-FULL.bak", Message: "db-FULL.bak has no backups in p"},
+	{Kind: EventUncovered, Path: "p", Message: "1 files in p are not covered by config", Files: []string{"other_2021-08-06.bak"}},
+}
+
+func TestWebhookNotifiers(t *testing.T) {
+	var gotEvents []Event
+	var gotText string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/webhook":
+			json.NewDecoder(r.Body).Decode(&gotEvents)
+		case "/slack":
+			msg := struct{ Text string }{}
+			json.NewDecoder(r.Body).Decode(&msg)
+			gotText = msg.Text
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	n, err := NewNotifiers([]NotifierConfig{
+		{Type: "webhook", URL: srv.URL + "/webhook"},
+		{Type: "slack", URL: srv.URL + "/slack"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(testEvents); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if !reflect.DeepEqual(gotEvents, testEvents) {
+		t.Errorf("webhook got %v, want %v", gotEvents, testEvents)
+	}
+	if !strings.Contains(gotText, "uncovered: 1 files in p are not covered by config\n\tother_2021-08-06.bak") {
+		t.Errorf("slack got %q", gotText)
+	}
+
+	n = &WebhookNotifier{URL: srv.URL + "/absent"}
+	if err := n.Notify(testEvents); err == nil {
+		t.Errorf("Notify() to absent url must fail")
+	}
+}
+
+// fakeSMTPServer accepts one mail and sends its data to a channel.
+func fakeSMTPServer(t *testing.T) (addr string, data chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = make(chan string, 1)
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 localhost ESMTP")
+		mail := ""
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					mail += line
+				}
+				reply("250 ok")
+			case cmd == "QUIT":
+				reply("221 bye")
+				data <- mail
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return l.Addr().String(), data
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	addr, data := fakeSMTPServer(t)
+	n, err := NewNotifier(NotifierConfig{Type: "smtp", Server: addr, From: "dblist@localhost", To: []string{"dba@localhost"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(testEvents); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	mail := <-data
+	for _, want := range []string{"To: dba@localhost\r\n", "Subject: dblist notification\r\n", "stale: db-FULL.bak has no backups in p\r\n"} {
+		if !strings.Contains(mail, want) {
+			t.Errorf("mail\n%s\nhas no %q", mail, want)
+		}
+	}
+}
+
+func TestNewNotifierErrors(t *testing.T) {
+	for _, c := range []NotifierConfig{
+		{Type: "webhook"},
+		{Type: "smtp", Server: "localhost:25"},
+		{Type: "pigeon"},
+	} {
+		if _, err := NewNotifier(c); err == nil {
+			t.Errorf("NewNotifier(%+v) must fail", c)
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	conf := []ConfigLine{
+		{Path: "p", Filename: "db", Suffix: "-FULL.bak", Days: 7},
+		{Path: "p", Filename: "db", Suffix: "-differ.bak", Days: 1},
+		{Path: "p", Filename: "nobackups", Suffix: "-FULL.bak", Days: 1},
+	}
+	filesByPath := map[string][]FileInfoWin{
+		"p": files(
+			"db_2021-08-08T21-00-05-001-FULL.bak",
+			"db_2021-08-09T21-00-05-001-differ.bak",
+			"other_2021-08-09T21-00-05-001-differ.bak",
+		),
+	}
+	now := mustparse("2021-08-12T21-00-00")
+
+	stale := StaleEvents(conf, filesByPath, now)
+	if len(stale) != 2 || stale[0].Suffix != "-differ.bak" || stale[1].DBName != "nobackups" {
+		t.Errorf("StaleEvents() = %v", stale)
+	}
+	uncovered := UncoveredEvents(conf, filesByPath, now)
+	if len(uncovered) != 1 || !reflect.DeepEqual(uncovered[0].Files, []string{"other_2021-08-09T21-00-05-001-differ.bak"}) {
+		t.Errorf("UncoveredEvents() = %v", uncovered)
+	}
+}
+
+func TestReadNotifyConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dblist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	configfile := filepath.Join(dir, "config.json")
+	err = ioutil.WriteFile(configfile, []byte(`{
+		"version": 2,
+		"databases": [{"path":"g:/ShebB", "Filename":"buh_log8", "Days":1}],
+		"notify": [{"Type":"webhook", "URL":"http://alerts/dblist"}]
+	}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf, err := ReadConfig(configfile)
+	if err != nil || len(conf) != 1 || conf[0].Filename != "buh_log8" {
+		t.Errorf("ReadConfig() = %v, %v", conf, err)
+	}
+	notify, err := ReadNotifyConfig(configfile)
+	if err != nil || !reflect.DeepEqual(notify, []NotifierConfig{{Type: "webhook", URL: "http://alerts/dblist"}}) {
+		t.Errorf("ReadNotifyConfig() = %v, %v", notify, err)
+	}
+}
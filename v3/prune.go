@@ -0,0 +1,106 @@
+package dblist
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// PruneOptions tune Prune.
+type PruneOptions struct {
+	Keep       uint        // copies kept in every group, see GetFilesToDelete
+	MaxPercent int         // limit of files deleted in a directory, see CheckDeletionPlan
+	DryRun     bool        // only plan, nothing is locked or deleted
+	Lock       LockOptions // locks of directories, see LockPath
+}
+
+// PrunedFile is a file deleted by Prune.
+type PrunedFile struct {
+	Path string
+	FileInfoWin
+}
+
+// PruneResult is what Prune did.
+type PruneResult struct {
+	Files  []PrunedFile // deleted files or files to delete with DryRun, ordered by path
+	Failed []string     // errors of deleting files
+}
+
+// Prune deletes files of every path that GetFilesToDelete returns, it deletes all of them or nothing.
+// Deletion plans of all paths are checked first, nothing is deleted if any plan is unsafe, see CheckDeletionPlan,
+// the error wraps ErrUnsafeDeletion then.
+// Then Prune takes locks of all paths with files to delete, see LockPath, reads them again and plans again,
+// so files that changed after filesByPath was read are not deleted by an outdated plan.
+// Nothing is deleted if any lock or new plan fails, the error is an error of LockPath or wraps ErrUnsafeDeletion.
+// Errors of deleting files are reported in PruneResult.Failed.
+func Prune(filesByPath map[string][]FileInfoWin, nameTosuffixes map[string][]string, o PruneOptions) (*PruneResult, error) {
+	paths := make([]string, 0, len(filesByPath))
+	for path := range filesByPath {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths) // the order of locks
+
+	plans := make(map[string][]FileInfoWin, len(paths))
+	for _, path := range paths {
+		todelete, err := planPrune(path, filesByPath[path], nameTosuffixes, o)
+		if err != nil {
+			return nil, err
+		}
+		plans[path] = todelete
+	}
+
+	ret := &PruneResult{Files: []PrunedFile{}}
+	if o.DryRun {
+		for _, path := range paths {
+			for _, f := range plans[path] {
+				ret.Files = append(ret.Files, PrunedFile{Path: path, FileInfoWin: f})
+			}
+		}
+		return ret, nil
+	}
+
+	locks := []*PathLock{}
+	defer func() {
+		for _, lock := range locks {
+			lock.Unlock()
+		}
+	}()
+	for _, path := range paths {
+		if len(plans[path]) == 0 {
+			continue
+		}
+		lock, err := LockPath(path, o.Lock)
+		if err != nil {
+			return nil, err
+		}
+		locks = append(locks, lock)
+		files, ok := ReadFilesFromPaths(map[string]int{path: 1})[path]
+		if !ok {
+			return nil, fmt.Errorf("%s: can't read directory", path)
+		}
+		if plans[path], err = planPrune(path, files, nameTosuffixes, o); err != nil {
+			return nil, err
+		}
+	}
+	for _, path := range paths {
+		for _, f := range plans[path] {
+			if err := os.Remove(filepath.Join(path, f.Name())); err != nil {
+				ret.Failed = append(ret.Failed, err.Error())
+				continue
+			}
+			ret.Files = append(ret.Files, PrunedFile{Path: path, FileInfoWin: f})
+		}
+	}
+	return ret, nil
+}
+
+// planPrune returns files of a path to delete, an unsafe plan is an error.
+func planPrune(path string, files []FileInfoWin, nameTosuffixes map[string][]string, o PruneOptions) ([]FileInfoWin, error) {
+	files = append([]FileInfoWin(nil), files...) // GetFilesToDelete sorts files
+	todelete := GetFilesToDelete(files, GroupFunc, nameTosuffixes, o.Keep)
+	if err := CheckDeletionPlan(files, todelete, GroupFunc, nameTosuffixes, o.MaxPercent); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return todelete, nil
+}
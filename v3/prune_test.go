@@ -0,0 +1,107 @@
+package dblist
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestPrune(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dblist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(names ...string) {
+		t.Helper()
+		for _, name := range names {
+			if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	exist := func() (ret []string) {
+		for _, f := range ReadFilesFromPaths(map[string]int{dir: 1})[dir] {
+			ret = append(ret, f.Name())
+		}
+		return ret
+	}
+	pruned := func(res *PruneResult) (ret []string) {
+		for _, f := range res.Files {
+			if f.Path != dir {
+				t.Errorf("pruned file path = %s, want %s", f.Path, dir)
+			}
+			ret = append(ret, f.Name())
+		}
+		return ret
+	}
+	write("db_2021-08-01T21-00-05-FULL.bak", "db_2021-08-08T21-00-05-FULL.bak", "db_2021-08-09T21-00-05-differ.dif")
+	nameTosuffixes := map[string][]string{"db": {"-FULL.bak", "-differ.dif"}}
+	filesByPath := ReadFilesFromPaths(map[string]int{dir: 1})
+	o := PruneOptions{Keep: 1, MaxPercent: 100, DryRun: true}
+
+	res, err := Prune(filesByPath, nameTosuffixes, o)
+	if want := []string{"db_2021-08-01T21-00-05-FULL.bak"}; err != nil || !reflect.DeepEqual(pruned(res), want) {
+		t.Errorf("Prune() dry run = %v, %v, want %v", res, err, want)
+	}
+	if len(exist()) != 3 {
+		t.Errorf("dry run deleted files, left %v", exist())
+	}
+
+	o.MaxPercent = 10
+	if _, err := Prune(filesByPath, nameTosuffixes, o); !errors.Is(err, ErrUnsafeDeletion) {
+		t.Errorf("Prune() of an unsafe plan error = %v, want %v", err, ErrUnsafeDeletion)
+	}
+
+	// files are planned again under the lock, a newer FULL backup came after filesByPath was read
+	write("db_2021-08-15T21-00-05-FULL.bak")
+	o.MaxPercent, o.DryRun = 100, false
+	res, err = Prune(filesByPath, nameTosuffixes, o)
+	want := []string{"db_2021-08-01T21-00-05-FULL.bak", "db_2021-08-08T21-00-05-FULL.bak"}
+	if err != nil || len(res.Failed) != 0 || !reflect.DeepEqual(pruned(res), want) {
+		t.Errorf("Prune() = %v, %v, want %v", res, err, want)
+	}
+	if left, want := exist(), []string{"db_2021-08-09T21-00-05-differ.dif", "db_2021-08-15T21-00-05-FULL.bak"}; !reflect.DeepEqual(left, want) {
+		t.Errorf("files left = %v, want %v", left, want)
+	}
+}
+
+func TestPruneLocked(t *testing.T) {
+	dirs := make([]string, 2)
+	filesByPath := make(map[string][]FileInfoWin)
+	for i := range dirs {
+		dir, err := ioutil.TempDir("", "dblist")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		for _, name := range []string{"db_2021-08-01T21-00-05-FULL.bak", "db_2021-08-08T21-00-05-FULL.bak"} {
+			if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		dirs[i] = dir
+		filesByPath[dir] = ReadFilesFromPaths(map[string]int{dir: 1})[dir]
+	}
+	// a locked directory deletes nothing in every directory, even in directories locked before it
+	sort.Strings(dirs)
+	lock, err := LockPath(dirs[1], LockOptions{Shared: true, Op: "upload"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Unlock()
+	nameTosuffixes := map[string][]string{"db": {"-FULL.bak"}}
+	o := PruneOptions{Keep: 1, MaxPercent: 100, Lock: LockOptions{Timeout: -1}}
+	if res, err := Prune(filesByPath, nameTosuffixes, o); !errors.Is(err, ErrLockTimeout) {
+		t.Errorf("Prune() of a locked directory = %v, %v, want %v", res, err, ErrLockTimeout)
+	}
+	for _, dir := range dirs {
+		if n := len(ReadFilesFromPaths(map[string]int{dir: 1})[dir]); n != 2 {
+			t.Errorf("%s has %d files after an aborted prune, want 2", dir, n)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// Package remote copies backup files to other storages: mirror directories and backends of uploaders.
+// A copy appears in a storage only complete and with a verified checksum,
+// so a storage never has partially written backup files under their names.
+package remote
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zavla/dblist/v3"
+)
+
+// ErrChecksum means a stored copy differs from its source.
+var ErrChecksum = errors.New("checksum mismatch")
+
+// PartialSuffix is appended to names of files being written to DirStorage.
+const PartialSuffix = ".partial"
+
+// Storage keeps copies of backup files under their base names.
+type Storage interface {
+	// List returns complete files of the storage.
+	List() ([]os.FileInfo, error)
+	// Create starts writing a file, the file appears in List only after Writer.Commit.
+	Create(name string) (Writer, error)
+	// Checksum returns hex sha256 of a stored file.
+	Checksum(name string) (string, error)
+	// Remove deletes a stored file.
+	Remove(name string) error
+	// String names the storage in messages.
+	String() string
+}
+
+// ResumableStorage continues writing files after failures, ex. of a network, see Uploader.
+type ResumableStorage interface {
+	Storage
+	// Resume continues writing a file after Writer.Close, it returns the size already written.
+	// It is Create with zero size if there is no written part.
+	Resume(name string) (Writer, int64, error)
+}
+
+// Writer writes a file to a storage.
+type Writer interface {
+	io.Writer
+	// Close stops writing and keeps the written part for ResumableStorage.Resume.
+	Close() error
+	// Commit checks that the written file has hex sha256 checksum and makes it visible under its name.
+	// An error wraps ErrChecksum if the written file differs, the file is discarded then.
+	Commit(checksum string) error
+	// Abort discards the written file.
+	Abort() error
+}
+
+// DirStorage is a directory, ex. on a secondary disk or a network share.
+// Files are written with PartialSuffix and renamed when complete.
+// Renames and removals hold the directory lock, see dblist.LockPath.
+type DirStorage struct {
+	Dir         string
+	LockTimeout time.Duration // dblist.LockOptions.Timeout
+}
+
+func (s *DirStorage) String() string { return s.Dir }
+
+func (s *DirStorage) lock(op string) (*dblist.PathLock, error) {
+	return dblist.LockPath(s.Dir, dblist.LockOptions{Timeout: s.LockTimeout, Op: op})
+}
+
+// List returns files of the directory without partial files and the lock file.
+// A directory that doesn't exist is empty.
+func (s *DirStorage) List() ([]os.FileInfo, error) {
+	all, err := ioutil.ReadDir(s.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]os.FileInfo, 0, len(all))
+	for _, fi := range all {
+		if fi.IsDir() || fi.Name() == dblist.LockFileName || strings.HasSuffix(fi.Name(), PartialSuffix) {
+			continue
+		}
+		ret = append(ret, fi)
+	}
+	return ret, nil
+}
+
+// Create creates the directory if needed and truncates a partial file of name.
+func (s *DirStorage) Create(name string) (Writer, error) {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return nil, err
+	}
+	filename := filepath.Join(s.Dir, name)
+	f, err := os.OpenFile(filename+PartialSuffix, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &dirWriter{s: s, f: f, filename: filename}, nil
+}
+
+// Resume opens a partial file of name for appending.
+func (s *DirStorage) Resume(name string) (Writer, int64, error) {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return nil, 0, err
+	}
+	filename := filepath.Join(s.Dir, name)
+	f, err := os.OpenFile(filename+PartialSuffix, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, 0, err
+	}
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return &dirWriter{s: s, f: f, filename: filename}, size, nil
+}
+
+// Checksum returns hex sha256 of a file in the directory.
+func (s *DirStorage) Checksum(name string) (string, error) {
+	return fileSHA256(filepath.Join(s.Dir, name))
+}
+
+// Remove deletes a file holding the directory lock.
+func (s *DirStorage) Remove(name string) error {
+	lock, err := s.lock("dblist remove")
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	return os.Remove(filepath.Join(s.Dir, name))
+}
+
+type dirWriter struct {
+	s        *DirStorage
+	f        *os.File
+	filename string // final name, the partial file has PartialSuffix
+}
+
+func (w *dirWriter) Write(b []byte) (int, error) {
+	return w.f.Write(b)
+}
+
+// Commit flushes the partial file to disk, reads it back and renames it holding the directory lock.
+func (w *dirWriter) Commit(checksum string) error {
+	partial := w.f.Name()
+	err := w.f.Sync()
+	if errclose := w.f.Close(); err == nil {
+		err = errclose
+	}
+	if err != nil {
+		os.Remove(partial)
+		return err
+	}
+	got, err := fileSHA256(partial)
+	if err == nil && got != checksum {
+		err = fmt.Errorf("%s: %w, written %s, want %s", w.filename, ErrChecksum, got, checksum)
+	}
+	if err != nil {
+		os.Remove(partial)
+		return err
+	}
+	lock, err := w.s.lock("dblist rename")
+	if err != nil {
+		return err // the partial file is complete, Resume continues with nothing to write
+	}
+	defer lock.Unlock()
+	return os.Rename(partial, w.filename)
+}
+
+func (w *dirWriter) Close() error {
+	return w.f.Close()
+}
+
+func (w *dirWriter) Abort() error {
+	w.f.Close()
+	return os.Remove(w.f.Name())
+}
+
+// fileSHA256 returns hex sha256 of a file.
+func fileSHA256(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,57 @@
+package remote
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDirStorage(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "dblist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	s := &DirStorage{Dir: filepath.Join(tmp, "mirror")}
+
+	if files, err := s.List(); err != nil || len(files) != 0 {
+		t.Fatalf("List of a new storage = %v, %v, want nothing", files, err)
+	}
+	w, err := s.Create("a.bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("backup"))
+	if files, _ := s.List(); len(files) != 0 {
+		t.Errorf("List shows a file being written: %v", files)
+	}
+	if err := w.Commit("0000"); !errors.Is(err, ErrChecksum) {
+		t.Errorf("Commit with a wrong checksum = %v, want ErrChecksum", err)
+	}
+	if _, err := os.Stat(filepath.Join(s.Dir, "a.bak"+PartialSuffix)); !os.IsNotExist(err) {
+		t.Errorf("partial file is kept after a wrong checksum")
+	}
+
+	w, _ = s.Create("a.bak")
+	w.Write([]byte("backup"))
+	ioutil.WriteFile(filepath.Join(tmp, "src"), []byte("backup"), 0644)
+	sum, _ := fileSHA256(filepath.Join(tmp, "src"))
+	if err := w.Commit(sum); err != nil {
+		t.Fatal(err)
+	}
+	files, err := s.List()
+	if err != nil || len(files) != 1 || files[0].Name() != "a.bak" {
+		t.Fatalf("List = %v, %v, want a.bak", files, err)
+	}
+	if got, err := s.Checksum("a.bak"); err != nil || got != sum {
+		t.Errorf("Checksum = %s, %v, want %s", got, err, sum)
+	}
+	if err := s.Remove("a.bak"); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := s.List(); len(files) != 0 {
+		t.Errorf("List after Remove = %v", files)
+	}
+}
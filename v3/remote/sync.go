@@ -0,0 +1,223 @@
+package remote
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zavla/dblist/v3"
+)
+
+// Target is a storage that keeps the newest files of every group, like a directory after 'dblist prune'.
+type Target struct {
+	Storage Storage
+	Keep    uint // copies of every group, 1 if zero
+	// Prune removes stored files of groups covered by config that are not among Keep newest files.
+	// Files not covered by config are never removed.
+	Prune bool
+	// Verify compares checksums of stored files with their sources, otherwise only sizes are compared.
+	// A stored file that differs is copied again.
+	Verify bool
+	// LockTimeout is dblist.LockOptions.Timeout of shared locks of source paths held while files are read.
+	LockTimeout time.Duration
+}
+
+// Source is a backup file in a config path.
+type Source struct {
+	Path string
+	dblist.FileInfoWin
+}
+
+// Filename returns the full name of the file.
+func (s Source) Filename() string {
+	return filepath.Join(s.Path, s.Name())
+}
+
+// Plan is what Sync does with a target.
+type Plan struct {
+	Copy   []Source // files missing in the storage or of different size, ordered by name
+	Keep   []Source // files already in the storage
+	Remove []string // names of stored files out of retention, only with Target.Prune
+}
+
+// SourceDirs returns a directory of a storage for every path: the path relative to the root of config paths,
+// so copies of different paths, ex. directories of a glob path, don't mix in a storage.
+// The root is the common parent of config line paths, a glob path contributes its part before the first pattern.
+// The directory of a config with a single path without patterns is ".", that is the storage itself.
+func SourceDirs(conf []dblist.ConfigLine, paths []string) map[string]string {
+	root := ""
+	for i, line := range conf {
+		dir := filepath.Clean(line.Path)
+		for dblist.IsGlobPath(dir) {
+			dir = filepath.Dir(dir)
+		}
+		if i == 0 {
+			root = dir
+			continue
+		}
+		for !within(root, dir) && filepath.Dir(root) != root {
+			root = filepath.Dir(root)
+		}
+	}
+	ret := make(map[string]string, len(paths))
+	for _, path := range paths {
+		rel, err := filepath.Rel(root, path)
+		if err != nil || !within(root, path) {
+			// not under config paths, ex. another volume
+			rel = strings.TrimLeft(strings.TrimPrefix(filepath.Clean(path), filepath.VolumeName(path)), `/\`)
+		}
+		ret[path] = rel
+	}
+	return ret
+}
+
+// within reports whether path is root or is in root.
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// PlanSync selects Keep newest files of every group among source files of a path and stored files.
+// The newest files are copied unless a stored file has the same name and size,
+// so a storage keeps the newest files even after they were deleted in the path.
+// A storage keeps files of one path, see SourceDirs, otherwise Prune removes copies of other paths.
+func PlanSync(path string, files []dblist.FileInfoWin, stored []os.FileInfo, nameTosuffixes map[string][]string, t Target) Plan {
+	keep := t.Keep
+	if keep == 0 {
+		keep = 1
+	}
+	sources := make(map[string]Source, len(files))
+	for _, f := range files {
+		sources[f.Name()] = Source{Path: path, FileInfoWin: f}
+	}
+	storedByName := make(map[string]os.FileInfo, len(stored))
+	all := make([]dblist.FileInfoWin, 0, len(sources)+len(stored))
+	for _, src := range sources {
+		all = append(all, src.FileInfoWin)
+	}
+	for _, fi := range stored {
+		storedByName[fi.Name()] = fi
+		if _, ok := sources[fi.Name()]; !ok {
+			all = append(all, dblist.FileInfoWin{FileInfo: fi})
+		}
+	}
+
+	plan := Plan{}
+	wanted := make(map[string]bool)
+	for _, f := range dblist.GetLastFilesGroupedByFunc(all, dblist.GroupFunc, nameTosuffixes, keep) {
+		wanted[f.Name()] = true
+		src, ok := sources[f.Name()]
+		if !ok {
+			continue // only in the storage
+		}
+		if fi, ok := storedByName[f.Name()]; ok && fi.Size() == src.Size() {
+			plan.Keep = append(plan.Keep, src)
+			continue
+		}
+		plan.Copy = append(plan.Copy, src)
+	}
+	if t.Prune {
+		for _, fi := range stored {
+			if _, suffix := dblist.GroupFunc(fi.Name(), nameTosuffixes); suffix != "" && !wanted[fi.Name()] {
+				plan.Remove = append(plan.Remove, fi.Name())
+			}
+		}
+		sort.Strings(plan.Remove)
+	}
+	sortSources(plan.Copy)
+	sortSources(plan.Keep)
+	return plan
+}
+
+func sortSources(s []Source) {
+	sort.Slice(s, func(i, j int) bool {
+		return s[i].Path < s[j].Path || s[i].Path == s[j].Path && s[i].Name() < s[j].Name()
+	})
+}
+
+// SyncResult is what Sync did.
+type SyncResult struct {
+	Copied  []Source
+	Removed []string
+	Failed  []string // errors of copying and removing files
+}
+
+// Sync makes the storage of t keep the files of a path selected by PlanSync.
+// Files are copied holding a shared lock of the path, see dblist.LockPath, so prune doesn't delete them meanwhile.
+// Files are removed only after all copies succeeded, so a storage never loses the newest files of a group
+// before it gets newer ones.
+// An error is returned if the storage can't be listed or some files failed, result has what was done anyway.
+func Sync(path string, files []dblist.FileInfoWin, nameTosuffixes map[string][]string, t Target) (*SyncResult, error) {
+	stored, err := t.Storage.List()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", t.Storage, err)
+	}
+	plan := PlanSync(path, files, stored, nameTosuffixes, t)
+	if t.Verify {
+		for _, src := range plan.Keep {
+			want, err := fileSHA256(src.Filename())
+			if err != nil {
+				continue // the source is gone, the stored copy is kept
+			}
+			if got, err := t.Storage.Checksum(src.Name()); err != nil || got != want {
+				plan.Copy = append(plan.Copy, src)
+			}
+		}
+		sortSources(plan.Copy)
+	}
+
+	ret := &SyncResult{}
+	if len(plan.Copy) != 0 {
+		lock, err := dblist.LockPath(path, dblist.LockOptions{Shared: true, Timeout: t.LockTimeout, Op: "dblist sync"})
+		if err != nil {
+			ret.Failed = append(ret.Failed, err.Error())
+		} else {
+			for _, src := range plan.Copy {
+				if err := copyFile(t.Storage, src); err != nil {
+					ret.Failed = append(ret.Failed, err.Error())
+					continue
+				}
+				ret.Copied = append(ret.Copied, src)
+			}
+			lock.Unlock()
+		}
+	}
+	if len(ret.Failed) == 0 {
+		for _, name := range plan.Remove {
+			if err := t.Storage.Remove(name); err != nil {
+				ret.Failed = append(ret.Failed, err.Error())
+				continue
+			}
+			ret.Removed = append(ret.Removed, name)
+		}
+	}
+	if len(ret.Failed) != 0 {
+		return ret, fmt.Errorf("%s: some files were not synced: %s", t.Storage, strings.Join(ret.Failed, "; "))
+	}
+	return ret, nil
+}
+
+// copyFile copies a source file to a storage computing its checksum on the way.
+func copyFile(s Storage, src Source) error {
+	f, err := os.Open(src.Filename())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w, err := s.Create(src.Name())
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(w, io.TeeReader(f, h)); err != nil {
+		w.Abort()
+		return fmt.Errorf("copy %s to %s: %w", src.Filename(), s, err)
+	}
+	return w.Commit(hex.EncodeToString(h.Sum(nil)))
+}
@@ -0,0 +1,227 @@
+package remote
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zavla/dblist/v3"
+)
+
+type testFile struct {
+	name string
+	size int64
+}
+
+func (f testFile) Name() string       { return f.name }
+func (f testFile) Size() int64        { return f.size }
+func (f testFile) Mode() os.FileMode  { return 0644 }
+func (f testFile) ModTime() time.Time { return time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC) }
+func (f testFile) IsDir() bool        { return false }
+func (f testFile) Sys() interface{}   { return nil }
+
+func stored(names ...string) []os.FileInfo {
+	ret := []os.FileInfo{}
+	for _, name := range names {
+		ret = append(ret, testFile{name, 1})
+	}
+	return ret
+}
+
+func names(sources []Source) []string {
+	ret := []string{}
+	for _, s := range sources {
+		ret = append(ret, s.Name())
+	}
+	return ret
+}
+
+var testSuffixes = map[string][]string{"db": {"-FULL.bak", "-differ.bak"}}
+
+func TestPlanSync(t *testing.T) {
+	files := []dblist.FileInfoWin{
+		{FileInfo: testFile{"db_2021-08-01T21-00-00-001-FULL.bak", 1}},
+		{FileInfo: testFile{"db_2021-08-08T21-00-00-001-FULL.bak", 1}},
+		{FileInfo: testFile{"db_2021-08-09T21-00-00-001-differ.bak", 1}},
+		{FileInfo: testFile{"db_2021-08-10T21-00-00-001-differ.bak", 1}},
+		{FileInfo: testFile{"other_2021-08-10T21-00-00-001-FULL.bak", 1}},
+	}
+	tests := []struct {
+		name       string
+		stored     []os.FileInfo
+		t          Target
+		wantCopy   []string
+		wantKeep   []string
+		wantRemove []string
+	}{
+		{name: "empty storage",
+			wantCopy: []string{"db_2021-08-08T21-00-00-001-FULL.bak", "db_2021-08-10T21-00-00-001-differ.bak"},
+		},
+		{name: "keep 2",
+			t: Target{Keep: 2},
+			wantCopy: []string{"db_2021-08-01T21-00-00-001-FULL.bak", "db_2021-08-08T21-00-00-001-FULL.bak",
+				"db_2021-08-09T21-00-00-001-differ.bak", "db_2021-08-10T21-00-00-001-differ.bak"},
+		},
+		{name: "prune outdated copies",
+			stored:     stored("db_2021-07-25T21-00-00-001-FULL.bak", "db_2021-08-08T21-00-00-001-FULL.bak", "readme.txt"),
+			t:          Target{Prune: true},
+			wantCopy:   []string{"db_2021-08-10T21-00-00-001-differ.bak"},
+			wantKeep:   []string{"db_2021-08-08T21-00-00-001-FULL.bak"},
+			wantRemove: []string{"db_2021-07-25T21-00-00-001-FULL.bak"},
+		},
+		{name: "no prune",
+			stored:   stored("db_2021-07-25T21-00-00-001-FULL.bak", "db_2021-08-08T21-00-00-001-FULL.bak"),
+			wantCopy: []string{"db_2021-08-10T21-00-00-001-differ.bak"},
+			wantKeep: []string{"db_2021-08-08T21-00-00-001-FULL.bak"},
+		},
+		{name: "newer file only in storage",
+			stored:   stored("db_2021-08-15T21-00-00-001-FULL.bak"),
+			t:        Target{Prune: true},
+			wantCopy: []string{"db_2021-08-10T21-00-00-001-differ.bak"},
+		},
+		{name: "different size",
+			stored:   []os.FileInfo{testFile{"db_2021-08-08T21-00-00-001-FULL.bak", 2}},
+			wantCopy: []string{"db_2021-08-08T21-00-00-001-FULL.bak", "db_2021-08-10T21-00-00-001-differ.bak"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := PlanSync("/src", files, tt.stored, testSuffixes, tt.t)
+			if got := names(plan.Copy); !reflect.DeepEqual(got, append([]string{}, tt.wantCopy...)) {
+				t.Errorf("Copy = %v, want %v", got, tt.wantCopy)
+			}
+			if got := names(plan.Keep); !reflect.DeepEqual(got, append([]string{}, tt.wantKeep...)) {
+				t.Errorf("Keep = %v, want %v", got, tt.wantKeep)
+			}
+			if !reflect.DeepEqual(plan.Remove, tt.wantRemove) {
+				t.Errorf("Remove = %v, want %v", plan.Remove, tt.wantRemove)
+			}
+		})
+	}
+}
+
+func TestSync(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "dblist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	src := filepath.Join(tmp, "src")
+	os.Mkdir(src, 0755)
+	for _, name := range []string{"db_2021-08-01T21-00-00-001-FULL.bak", "db_2021-08-08T21-00-00-001-FULL.bak"} {
+		if err := ioutil.WriteFile(filepath.Join(src, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	target := Target{Storage: &DirStorage{Dir: filepath.Join(tmp, "mirror")}, Keep: 2, Prune: true, Verify: true}
+
+	res, err := Sync(src, dblist.ReadFilesFromPaths(map[string]int{src: 0})[src], testSuffixes, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Copied) != 2 {
+		t.Errorf("Sync copied %v, want 2 files", names(res.Copied))
+	}
+	res, err = Sync(src, dblist.ReadFilesFromPaths(map[string]int{src: 0})[src], testSuffixes, target)
+	if err != nil || len(res.Copied) != 0 {
+		t.Errorf("Sync of a synced storage copied %v, %v", names(res.Copied), err)
+	}
+
+	// a damaged copy of the same size is copied again
+	damaged := filepath.Join(tmp, "mirror", "db_2021-08-08T21-00-00-001-FULL.bak")
+	ioutil.WriteFile(damaged, []byte("db_2021-08-08T21-00-00-001-FULL.BAK"), 0644)
+	// a newer backup makes the oldest copy outdated
+	newest := "db_2021-08-15T21-00-00-001-FULL.bak"
+	ioutil.WriteFile(filepath.Join(src, newest), []byte(newest), 0644)
+	res, err = Sync(src, dblist.ReadFilesFromPaths(map[string]int{src: 0})[src], testSuffixes, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(res.Copied); !reflect.DeepEqual(got, []string{"db_2021-08-08T21-00-00-001-FULL.bak", newest}) {
+		t.Errorf("Sync copied %v, want the damaged and the newest files", got)
+	}
+	if want := []string{"db_2021-08-01T21-00-00-001-FULL.bak"}; !reflect.DeepEqual(res.Removed, want) {
+		t.Errorf("Sync removed %v, want %v", res.Removed, want)
+	}
+	if b, _ := ioutil.ReadFile(damaged); string(b) != "db_2021-08-08T21-00-00-001-FULL.bak" {
+		t.Errorf("damaged copy has %q", b)
+	}
+}
+
+func TestSourceDirs(t *testing.T) {
+	sep := string(filepath.Separator)
+	tests := []struct {
+		name  string
+		conf  []string
+		paths []string
+		want  []string
+	}{
+		{"one path", []string{"/mnt/sheb"}, []string{"/mnt/sheb"}, []string{"."}},
+		{"glob path", []string{"/mnt/*/sheb"}, []string{"/mnt/a/sheb", "/mnt/b/sheb"}, []string{"a" + sep + "sheb", "b" + sep + "sheb"}},
+		{"several paths", []string{"/mnt/sheb", "/mnt/buh"}, []string{"/mnt/buh", "/mnt/sheb"}, []string{"buh", "sheb"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := []dblist.ConfigLine{}
+			for _, path := range tt.conf {
+				conf = append(conf, dblist.ConfigLine{Path: filepath.FromSlash(path), Filename: "db", Suffix: "-FULL.bak"})
+			}
+			paths := []string{}
+			for _, path := range tt.paths {
+				paths = append(paths, filepath.FromSlash(path))
+			}
+			dirs := SourceDirs(conf, paths)
+			got := []string{}
+			for _, path := range paths {
+				got = append(got, dirs[path])
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SourceDirs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyncPaths(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "dblist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	// the same database on two servers, their files must not replace each other in a mirror
+	srcFiles := map[string]string{
+		filepath.Join(tmp, "a", "sheb"): "db_2021-08-01T21-00-00-001-FULL.bak",
+		filepath.Join(tmp, "b", "sheb"): "db_2021-08-08T21-00-00-001-FULL.bak",
+	}
+	for dir, name := range srcFiles {
+		os.MkdirAll(dir, 0755)
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(dir), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conf := []dblist.ConfigLine{{Path: filepath.Join(tmp, "*", "sheb"), Filename: "db", Suffix: "-FULL.bak"}}
+	filesByPath := dblist.ReadFilesFromPaths(dblist.GetUniquePaths(conf))
+	paths := []string{}
+	for path := range filesByPath {
+		paths = append(paths, path)
+	}
+	mirror := filepath.Join(tmp, "mirror")
+	dirs := SourceDirs(conf, paths)
+	for i := 0; i < 2; i++ { // the second sync prunes nothing
+		for _, path := range paths {
+			target := Target{Storage: &DirStorage{Dir: filepath.Join(mirror, dirs[path])}, Prune: true}
+			if _, err := Sync(path, filesByPath[path], testSuffixes, target); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	for dir, name := range srcFiles {
+		rel, _ := filepath.Rel(tmp, dir)
+		if b, err := ioutil.ReadFile(filepath.Join(mirror, rel, name)); err != nil || string(b) != dir {
+			t.Errorf("mirror copy of %s has %q, %v", filepath.Join(dir, name), b, err)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package remote
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zavla/dblist/v3"
+)
+
+// Defaults of Uploader.
+const (
+	DefaultChunkSize  = 8 << 20
+	DefaultRetries    = 5
+	DefaultRetryDelay = 5 * time.Second
+)
+
+// throttleBlock is the largest read of a throttled upload, so a slow limit doesn't cause long pauses.
+const throttleBlock = 32 << 10
+
+// ErrChanged means a file changed while it was uploaded, ex. a backup was still being written.
+var ErrChanged = errors.New("file changed during upload")
+
+// Uploader uploads backup files that are not uploaded yet and marks them uploaded, see dblist.MarkUploaded.
+type Uploader struct {
+	// Storage returns the storage of files of a source path, ex. a directory of SourceDirs,
+	// so files of different paths with the same name don't replace each other.
+	Storage func(path string) ResumableStorage
+	// Concurrency is how many files are uploaded at once, 1 if zero.
+	Concurrency int
+	// BytesPerSecond limits all uploads together, no limit if zero.
+	BytesPerSecond int64
+	// ChunkSize is the size of writes to the storage, DefaultChunkSize if zero.
+	// A failed upload resumes after the data the storage has got.
+	ChunkSize int
+	// Retries is how many times a failed upload is resumed, DefaultRetries if zero, no retries if negative.
+	Retries int
+	// RetryDelay is a delay before the first retry, DefaultRetryDelay if zero, it doubles every retry.
+	RetryDelay time.Duration
+	// LockTimeout is dblist.LockOptions.Timeout of locks of source paths.
+	LockTimeout time.Duration
+}
+
+// Queue returns files to upload: files covered by config that are not uploaded yet, see dblist.FileInfoWin.IsUploaded.
+// The oldest files are first, so a FULL backup is uploaded before later backups that need it.
+func Queue(filesByPath map[string][]dblist.FileInfoWin, nameTosuffixes map[string][]string) []Source {
+	ret := []Source{}
+	for path, files := range filesByPath {
+		for _, f := range files {
+			if _, suffix := dblist.GroupFunc(f.Name(), nameTosuffixes); suffix != "" && !f.IsUploaded() {
+				ret = append(ret, Source{Path: path, FileInfoWin: f})
+			}
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		ti, tj := dblist.BackupTime(ret[i].FileInfoWin), dblist.BackupTime(ret[j].FileInfoWin)
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return ret[i].Path < ret[j].Path || ret[i].Path == ret[j].Path && ret[i].Name() < ret[j].Name()
+	})
+	return ret
+}
+
+// UploadResult is what Upload did.
+type UploadResult struct {
+	Uploaded []Source // uploaded and marked files, their WinAttr has no A attribute, ordered by path and name
+	Failed   []string // errors of files
+}
+
+// Upload uploads files in their order and marks them uploaded.
+// A file is read and marked holding a shared lock of its path, see dblist.LockPath, so prune doesn't delete it meanwhile
+// and other uploads of the path go on. A file whose copy is already in its storage is only marked,
+// ex. a file uploaded by a run that failed to mark it.
+// A file that changed during upload is not marked, it fails with ErrChanged.
+// When ctx is done Upload doesn't start new files and retries, running uploads stop and resume on the next call.
+// An error is returned if some files failed or ctx is done, result has what was done anyway.
+func (u *Uploader) Upload(ctx context.Context, files []Source) (*UploadResult, error) {
+	n := u.Concurrency
+	if n <= 0 {
+		n = 1
+	}
+	lim := &limiter{rate: u.BytesPerSecond}
+	ret := &UploadResult{}
+	mu := sync.Mutex{}
+	queue := make(chan Source)
+	wg := sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for src := range queue {
+				err := u.upload(ctx, lim, src)
+				mu.Lock()
+				if err != nil {
+					ret.Failed = append(ret.Failed, err.Error())
+				} else {
+					src.WinAttr &^= 0x20
+					ret.Uploaded = append(ret.Uploaded, src)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+loop:
+	for _, src := range files {
+		select {
+		case queue <- src:
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	sortSources(ret.Uploaded)
+	if len(ret.Failed) != 0 {
+		return ret, fmt.Errorf("some files were not uploaded: %s", strings.Join(ret.Failed, "; "))
+	}
+	return ret, ctx.Err()
+}
+
+// upload uploads a file retrying failed attempts and marks it uploaded.
+// The mark is an attribute of the file that readers holding shared locks don't depend on,
+// so it is set under the shared lock, an exclusive lock would wait for other uploads of the path.
+func (u *Uploader) upload(ctx context.Context, lim *limiter, src Source) error {
+	lock, err := dblist.LockPath(src.Path, dblist.LockOptions{Shared: true, Timeout: u.LockTimeout, Op: "dblist upload"})
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	s := u.Storage(src.Path)
+	if !committed(s, src) {
+		if err := u.transfer(ctx, lim, s, src); err != nil {
+			return err
+		}
+	}
+	return dblist.MarkUploaded(src.Filename())
+}
+
+// committed reports whether a storage has a complete copy of a file.
+func committed(s Storage, src Source) bool {
+	got, err := s.Checksum(src.Name())
+	if err != nil {
+		return false
+	}
+	want, err := fileSHA256(src.Filename())
+	return err == nil && got == want
+}
+
+func (u *Uploader) transfer(ctx context.Context, lim *limiter, s ResumableStorage, src Source) error {
+	retries := u.Retries
+	if retries == 0 {
+		retries = DefaultRetries
+	}
+	delay := u.RetryDelay
+	if delay == 0 {
+		delay = DefaultRetryDelay
+	}
+	for attempt := 1; ; attempt++ {
+		err := u.attempt(ctx, lim, s, src)
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, ErrChanged) || ctx.Err() != nil || attempt > retries {
+			return fmt.Errorf("upload %s to %s, attempt %d: %w", src.Filename(), s, attempt, err)
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return fmt.Errorf("upload %s to %s, attempt %d: %w", src.Filename(), s, attempt, err)
+		}
+		delay *= 2
+	}
+}
+
+// attempt resumes an upload after the data the storage has got and commits it with the checksum of the whole file.
+func (u *Uploader) attempt(ctx context.Context, lim *limiter, s ResumableStorage, src Source) error {
+	f, err := os.Open(src.Filename())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w, offset, err := s.Resume(src.Name())
+	if err != nil {
+		return err
+	}
+	if offset > src.Size() {
+		w.Abort()
+		if w, err = s.Create(src.Name()); err != nil {
+			return err
+		}
+		offset = 0
+	}
+	h := sha256.New()
+	if _, err := io.CopyN(h, f, offset); err != nil {
+		w.Close()
+		return err
+	}
+
+	chunk := u.ChunkSize
+	if chunk <= 0 {
+		chunk = DefaultChunkSize
+	}
+	buf := make([]byte, chunk)
+	r := &throttledReader{ctx: ctx, r: f, lim: lim}
+	for {
+		if err := ctx.Err(); err != nil {
+			w.Close()
+			return err
+		}
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			h.Write(buf[:n])
+			if _, err := w.Write(buf[:n]); err != nil {
+				w.Close()
+				return err
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			w.Close()
+			return err
+		}
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		w.Close()
+		return err
+	}
+	if fi.Size() != src.Size() || !fi.ModTime().Equal(src.ModTime()) {
+		w.Abort()
+		return fmt.Errorf("%s: %w", src.Filename(), ErrChanged)
+	}
+	return w.Commit(hex.EncodeToString(h.Sum(nil)))
+}
+
+// limiter paces reads of all uploads to rate bytes per second, it doesn't limit if rate is zero.
+type limiter struct {
+	rate int64
+	mu   sync.Mutex
+	next time.Time // when the bytes allowed so far are sent at the rate
+}
+
+// wait waits until n more bytes may be sent.
+func (l *limiter) wait(ctx context.Context, n int) error {
+	if l.rate <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	l.next = l.next.Add(time.Duration(int64(n) * int64(time.Second) / l.rate))
+	d := l.next.Sub(now)
+	l.mu.Unlock()
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type throttledReader struct {
+	ctx context.Context
+	r   io.Reader
+	lim *limiter
+}
+
+func (tr *throttledReader) Read(p []byte) (int, error) {
+	if tr.lim.rate > 0 && len(p) > throttleBlock {
+		p = p[:throttleBlock]
+	}
+	n, err := tr.r.Read(p)
+	if n > 0 {
+		if errwait := tr.lim.wait(tr.ctx, n); errwait != nil {
+			return n, errwait
+		}
+	}
+	return n, err
+}
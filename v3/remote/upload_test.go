@@ -0,0 +1,137 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zavla/dblist/v3"
+)
+
+// flakyStorage fails the second write of every file once, like a dropped connection.
+type flakyStorage struct {
+	DirStorage
+	mu      sync.Mutex
+	failed  map[string]bool
+	resumed map[string]int64 // name to offset of the last Resume
+}
+
+func (s *flakyStorage) Resume(name string) (Writer, int64, error) {
+	w, offset, err := s.DirStorage.Resume(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resumed[name] = offset
+	fail := !s.failed[name]
+	s.failed[name] = true
+	return &flakyWriter{Writer: w, fail: fail}, offset, nil
+}
+
+type flakyWriter struct {
+	Writer
+	fail   bool
+	writes int
+}
+
+func (w *flakyWriter) Write(b []byte) (int, error) {
+	w.writes++
+	if w.fail && w.writes == 2 {
+		return 0, errors.New("connection reset")
+	}
+	return w.Writer.Write(b)
+}
+
+func TestUpload(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "dblist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	src := filepath.Join(tmp, "src")
+	os.Mkdir(src, 0755)
+	files := []string{"db_2021-08-08T21-00-00-001-FULL.bak", "db_2021-08-09T21-00-00-001-differ.bak", "other.txt"}
+	for _, name := range files {
+		if err := ioutil.WriteFile(filepath.Join(src, name), bytes.Repeat([]byte(name), 100), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := dblist.MarkUploaded(filepath.Join(src, "other.txt")); err != nil {
+		t.Skipf("file system doesn't keep upload marks: %v", err)
+	}
+
+	queue := Queue(dblist.ReadFilesFromPaths(map[string]int{src: 0}), testSuffixes)
+	if len(queue) != 2 || queue[0].Name() != files[0] || queue[1].Name() != files[1] {
+		t.Fatalf("Queue = %v, want backup files oldest first", queue)
+	}
+	storage := &flakyStorage{DirStorage: DirStorage{Dir: filepath.Join(tmp, "remote")},
+		failed: make(map[string]bool), resumed: make(map[string]int64)}
+	// uploads of a path don't wait for each other
+	u := &Uploader{Storage: func(string) ResumableStorage { return storage }, Concurrency: 2, ChunkSize: 1000,
+		RetryDelay: time.Millisecond, LockTimeout: -1}
+	res, err := u.Upload(context.Background(), queue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Uploaded) != 2 || !res.Uploaded[0].IsUploaded() {
+		t.Errorf("Upload uploaded %v, want 2 marked files", names(res.Uploaded))
+	}
+	for _, name := range files[:2] {
+		if storage.resumed[name] != 1000 {
+			t.Errorf("%s resumed at %d, want after the first chunk", name, storage.resumed[name])
+		}
+		want, _ := ioutil.ReadFile(filepath.Join(src, name))
+		if got, _ := ioutil.ReadFile(filepath.Join(storage.Dir, name)); !bytes.Equal(got, want) {
+			t.Errorf("uploaded %s differs", name)
+		}
+	}
+	if queue := Queue(dblist.ReadFilesFromPaths(map[string]int{src: 0}), testSuffixes); len(queue) != 0 {
+		t.Errorf("Queue after Upload = %v, want nothing", queue)
+	}
+
+	// files uploaded but not marked are only marked
+	storage.resumed = make(map[string]int64)
+	if res, err := u.Upload(context.Background(), queue); err != nil || len(res.Uploaded) != 2 || len(storage.resumed) != 0 {
+		t.Errorf("Upload of uploaded files = %v, %v, resumed %v, want 2 files without uploads", res, err, storage.resumed)
+	}
+
+	// a backup being written is not marked
+	name := "db_2021-08-10T21-00-00-001-differ.bak"
+	filename := filepath.Join(src, name)
+	ioutil.WriteFile(filename, []byte("part"), 0644)
+	queue = Queue(dblist.ReadFilesFromPaths(map[string]int{src: 0}), testSuffixes)
+	ioutil.WriteFile(filename, []byte("part and the rest"), 0644)
+	u.Retries = -1
+	if _, err := u.Upload(context.Background(), queue); err == nil || !strings.Contains(err.Error(), ErrChanged.Error()) {
+		t.Errorf("Upload of a changing file = %v, want ErrChanged", err)
+	}
+	if queue := Queue(dblist.ReadFilesFromPaths(map[string]int{src: 0}), testSuffixes); len(queue) != 1 {
+		t.Errorf("Queue after a changed file = %v, want the changed file", queue)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	l := &limiter{rate: 1000}
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.wait(context.Background(), 100); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d < 300*time.Millisecond {
+		t.Errorf("300 bytes at 1000 bytes per second took %s", d)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.wait(ctx, 1000); err != context.Canceled {
+		t.Errorf("wait of a cancelled context = %v", err)
+	}
+}
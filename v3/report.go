@@ -0,0 +1,256 @@
+package dblist
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// DatabaseReport is a summary of backup files of one database in one directory.
+type DatabaseReport struct {
+	Path            string    `json:"path"`
+	DBName          string    `json:"dbname"`
+	Configured      bool      `json:"configured"`  // database is in config json file
+	NewestFull      time.Time `json:"newest_full"` // zero if there is no FULL backup
+	NewestDiff      time.Time `json:"newest_diff"` // zero if there is no other backup
+	Files           int       `json:"files"`
+	TotalSize       int64     `json:"total_size"`
+	ReclaimableSize int64     `json:"reclaimable_size"` // size of files that retention would delete
+	Uncovered       int       `json:"uncovered"`        // number of files not covered by config json file
+}
+
+// Report is a summary of all backup files found in config paths.
+type Report struct {
+	Databases      []DatabaseReport `json:"databases"`
+	UncoveredFiles []string         `json:"uncovered_files"` // full file names
+}
+
+// BackupTime returns time of a backup from its file name.
+// If a file name has no time in it, file modification time is returned as WallClock.
+func BackupTime(fi FileInfoWin) time.Time {
+	return BackupTimeIn(fi, time.Local)
+}
+
+// BackupTimeIn is BackupTime of a file whose name has time in loc, modification time is returned as WallClockIn loc.
+// A nil loc is the local time zone.
+func BackupTimeIn(fi FileInfoWin, loc *time.Location) time.Time {
+	t, err := ExtractTimeFromFilename(fi.Name())
+	if err != nil {
+		return WallClockIn(fi.ModTime(), loc)
+	}
+	return t
+}
+
+// WallClock returns local wall clock of t in UTC location.
+// Times in file names have no time zone and ExtractTimeFromFilename returns them this way,
+// so use WallClock(time.Now()) to compare with them.
+func WallClock(t time.Time) time.Time {
+	return WallClockIn(t, time.Local)
+}
+
+// WallClockIn returns wall clock of t in loc in UTC location, see WallClock. A nil loc is the local time zone.
+func WallClockIn(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		loc = time.Local
+	}
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// confLocation returns time zone of times in file names of config lines, see ConfigLine.Location.
+func confLocation(conf []ConfigLine) *time.Location {
+	for _, line := range conf {
+		if line.Location != nil {
+			return line.Location
+		}
+	}
+	return time.Local
+}
+
+// BuildReport summarizes files read by ReadFilesFromPaths for every database.
+// Retention keeps keepLastNcopies in every group, as GetLastFilesGroupedByFunc does.
+// Databases from config json file without any files are reported too.
+func BuildReport(conf []ConfigLine, filesByPath map[string][]FileInfoWin, keepLastNcopies uint) Report {
+	nameTosuffixes := GetMapFilenameToSuffixes(conf)
+	loc := confLocation(conf)
+	rows := make(map[[2]string]*DatabaseReport)
+	row := func(path, dbname string) *DatabaseReport {
+		key := [2]string{path, dbname}
+		if r, ok := rows[key]; ok {
+			return r
+		}
+		_, configured := MatchDBName(dbname, nameTosuffixes)
+		r := &DatabaseReport{Path: path, DBName: dbname, Configured: configured}
+		rows[key] = r
+		return r
+	}
+	for _, line := range conf {
+		if IsDBNamePattern(line.Filename) {
+			continue // rows of matched databases are added by their files
+		}
+		for _, path := range PathsOfConfigLine(line, filesByPath) {
+			row(path, line.Filename)
+		}
+	}
+
+	rep := Report{Databases: []DatabaseReport{}, UncoveredFiles: []string{}}
+	for path, files := range filesByPath {
+		reclaimable := make(map[string]bool)
+		for _, f := range GetFilesToDelete(files, GroupFunc, nameTosuffixes, keepLastNcopies) {
+			reclaimable[f.Name()] = true
+		}
+		for _, f := range files {
+			dbname, suffix := GroupFunc(f.Name(), nameTosuffixes)
+			if dbname == "" || suffix == constFileNameHasWrongSuffix {
+				rep.UncoveredFiles = append(rep.UncoveredFiles, filepath.Join(path, f.Name()))
+			}
+			if dbname == "" {
+				continue // not a database backup file
+			}
+			r := row(path, dbname)
+			r.Files++
+			r.TotalSize += f.Size()
+			if suffix == constFileNameHasWrongSuffix {
+				r.Uncovered++
+				continue
+			}
+			t := BackupTimeIn(f, loc)
+			if IsFullBackupSuffix(suffix) {
+				if t.After(r.NewestFull) {
+					r.NewestFull = t
+				}
+			} else if t.After(r.NewestDiff) {
+				r.NewestDiff = t
+			}
+			if reclaimable[f.Name()] {
+				r.ReclaimableSize += f.Size()
+			}
+		}
+	}
+
+	for _, r := range rows {
+		rep.Databases = append(rep.Databases, *r)
+	}
+	sort.Slice(rep.Databases, func(i, j int) bool {
+		if rep.Databases[i].DBName != rep.Databases[j].DBName {
+			return rep.Databases[i].DBName < rep.Databases[j].DBName
+		}
+		return rep.Databases[i].Path < rep.Databases[j].Path
+	})
+	sort.Strings(rep.UncoveredFiles)
+	return rep
+}
+
+// WriteJSON writes the report as json.
+func (rep Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rep)
+}
+
+// csvTimeLayout has no time zone, report times are wall clock, see WallClock.
+const csvTimeLayout = "2006-01-02T15:04:05"
+
+// WriteCSV writes databases of the report as csv with a header line.
+// Sizes are in bytes, times are wall clock like times in file names, ex. 2021-08-06T17:47:01, without a time zone.
+func (rep Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"path", "dbname", "configured", "newest_full", "newest_diff",
+		"files", "total_size", "reclaimable_size", "uncovered"})
+	for _, r := range rep.Databases {
+		cw.Write([]string{
+			r.Path,
+			r.DBName,
+			strconv.FormatBool(r.Configured),
+			formatReportTime(r.NewestFull, csvTimeLayout),
+			formatReportTime(r.NewestDiff, csvTimeLayout),
+			strconv.Itoa(r.Files),
+			strconv.FormatInt(r.TotalSize, 10),
+			strconv.FormatInt(r.ReclaimableSize, 10),
+			strconv.Itoa(r.Uncovered),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteMarkdown writes the report as markdown tables.
+func (rep Report) WriteMarkdown(w io.Writer) error {
+	ew := &errWriter{w: w}
+	ew.printf("| Database | Path | Newest FULL | Newest diff | Files | Size | Reclaimable | Uncovered |\n")
+	ew.printf("|---|---|---|---|---:|---:|---:|---:|\n")
+	for _, r := range rep.Databases {
+		dbname := r.DBName
+		if !r.Configured {
+			dbname += " (not in config)"
+		}
+		ew.printf("| %s | %s | %s | %s | %d | %s | %s | %d |\n",
+			markdownEscape(dbname),
+			markdownEscape(r.Path),
+			formatReportTime(r.NewestFull, "2006-01-02 15:04"),
+			formatReportTime(r.NewestDiff, "2006-01-02 15:04"),
+			r.Files,
+			FormatSize(r.TotalSize),
+			FormatSize(r.ReclaimableSize),
+			r.Uncovered)
+	}
+	if len(rep.UncoveredFiles) != 0 {
+		ew.printf("\nFiles not covered by config:\n\n")
+		for _, name := range rep.UncoveredFiles {
+			ew.printf("- %s\n", markdownEscape(name))
+		}
+	}
+	return ew.err
+}
+
+// FormatSize formats a size in bytes for humans, ex. 1.5 GiB.
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+func formatReportTime(t time.Time, layout string) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(layout)
+}
+
+// markdownEscape escapes characters that break markdown tables.
+func markdownEscape(s string) string {
+	ret := make([]rune, 0, len(s))
+	for _, r := range s {
+		switch r {
+		case '|', '\\', '*', '_', '`':
+			ret = append(ret, '\\')
+		}
+		ret = append(ret, r)
+	}
+	return string(ret)
+}
+
+// errWriter remembers the first write error, so a sequence of prints is checked once.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, a ...interface{}) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, a...)
+}
@@ -0,0 +1,124 @@
+package dblist
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func reportTestData() ([]ConfigLine, map[string][]FileInfoWin) {
+	conf := []ConfigLine{
+		{Path: "p", Filename: "зп_в_камин", Suffix: "-FULL.rar"},
+		{Path: "p", Filename: "зп_в_камин", Suffix: "-differ.rar"},
+		{Path: "p", Filename: "nobackups", Suffix: "-FULL.bak"},
+	}
+	filesByPath := map[string][]FileInfoWin{
+		"p": files(
+			"зп_в_камин_2021-08-09T10-04-00-750-differ.rar",
+			"зп_в_камин_2021-08-10T10-04-00-717-differ.rar",
+			"зп_в_камин_2021-08-01T17-47-03-337-FULL.rar",
+			"зп_в_камин_2021-08-06T17-47-01-147-FULL.rar",
+			"зп_в_камин_2021-08-06T17-47-01-147-FULL.zip",
+			"other_2021-08-06T17-47-01-147-FULL.bak",
+		),
+	}
+	return conf, filesByPath
+}
+
+func TestBuildReport(t *testing.T) {
+	conf, filesByPath := reportTestData()
+	got := BuildReport(conf, filesByPath, 1)
+	want := Report{
+		Databases: []DatabaseReport{
+			{Path: "p", DBName: "nobackups", Configured: true},
+			{Path: "p", DBName: "other", Files: 1, TotalSize: 1, Uncovered: 1},
+			{Path: "p", DBName: "зп_в_камин", Configured: true,
+				NewestFull:      mustparse("2021-08-06T17-47-01"),
+				NewestDiff:      mustparse("2021-08-10T10-04-00"),
+				Files:           5,
+				TotalSize:       5,
+				ReclaimableSize: 2,
+				Uncovered:       1,
+			},
+		},
+		UncoveredFiles: []string{
+			"p/other_2021-08-06T17-47-01-147-FULL.bak",
+			"p/зп_в_камин_2021-08-06T17-47-01-147-FULL.zip",
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BuildReport() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReportWriters(t *testing.T) {
+	conf, filesByPath := reportTestData()
+	rep := BuildReport(conf, filesByPath, 1)
+
+	b := &bytes.Buffer{}
+	if err := rep.WriteCSV(b); err != nil {
+		t.Fatal(err)
+	}
+	wantCSV := "path,dbname,configured,newest_full,newest_diff,files,total_size,reclaimable_size,uncovered\n" +
+		"p,nobackups,true,,,0,0,0,0\n" +
+		"p,other,false,,,1,1,0,1\n" +
+		"p,зп_в_камин,true,2021-08-06T17:47:01,2021-08-10T10:04:00,5,5,2,1\n"
+	if b.String() != wantCSV {
+		t.Errorf("WriteCSV() =\n%s\nwant\n%s", b, wantCSV)
+	}
+
+	b.Reset()
+	if err := rep.WriteMarkdown(b); err != nil {
+		t.Fatal(err)
+	}
+	wantLine := "| зп\\_в\\_камин | p | 2021-08-06 17:47 | 2021-08-10 10:04 | 5 | 5 B | 2 B | 1 |\n"
+	if !strings.Contains(b.String(), wantLine) {
+		t.Errorf("WriteMarkdown() =\n%s\nwant line\n%s", b, wantLine)
+	}
+
+	b.Reset()
+	if err := rep.WriteJSON(b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), `"reclaimable_size": 2`) {
+		t.Errorf("WriteJSON() =\n%s", b)
+	}
+}
+
+type modtimeFI struct {
+	SubstFI
+	modtime time.Time
+}
+
+func (f modtimeFI) ModTime() time.Time { return f.modtime }
+
+func TestBackupTimeIn(t *testing.T) {
+	loc := time.FixedZone("UTC+3", 3*60*60)
+	fi := FileInfoWin{FileInfo: modtimeFI{SubstFI{mName: "db-FULL.bak"}, time.Date(2021, 8, 6, 15, 0, 0, 0, time.UTC)}}
+	if got, want := BackupTimeIn(fi, loc), time.Date(2021, 8, 6, 18, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("BackupTimeIn of modification time = %v, want wall clock %v", got, want)
+	}
+	fi = FileInfoWin{FileInfo: SubstFI{mName: "db_2021-08-06T17-47-01-147-FULL.bak"}}
+	if got, want := BackupTimeIn(fi, loc), mustparse("2021-08-06T17-47-01"); got.Truncate(time.Second) != want {
+		t.Errorf("BackupTimeIn of a file name = %v, want %v", got, want)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		size int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1536, "1.5 KiB"},
+		{3 << 30, "3.0 GiB"},
+	}
+	for _, tt := range tests {
+		if got := FormatSize(tt.size); got != tt.want {
+			t.Errorf("FormatSize(%d) = %v, want %v", tt.size, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package dblist
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrNoFullBackup means a database has no FULL backup to restore from.
+var ErrNoFullBackup = errors.New("no FULL backup")
+
+// RestoreChain returns files needed to restore a database as it was at a time:
+// the newest FULL backup not newer than at, the newest backup of every differential suffix
+// between that FULL backup and at, and every log backup after the newest of them up to at, see IsLogBackupSuffix.
+// Differential backups are cumulative, so older ones are not needed, log backups are not.
+// Zero at means the latest state. files are files of one directory, times are compared by BackupTime.
+// Files are ordered by backup time, the FULL backup is the first.
+func RestoreChain(files []FileInfoWin, dbname string, nameTosuffixes map[string][]string, at time.Time) ([]FileInfoWin, error) {
+	return RestoreChainIn(files, dbname, nameTosuffixes, at, time.Local)
+}
+
+// RestoreChainIn is RestoreChain of files whose names have time in loc, times are compared by BackupTimeIn.
+// at is a wall clock in loc, see WallClockIn. A nil loc is the local time zone.
+func RestoreChainIn(files []FileInfoWin, dbname string, nameTosuffixes map[string][]string, at time.Time, loc *time.Location) ([]FileInfoWin, error) {
+	var full *FileInfoWin
+	var fullTime time.Time
+	for i, f := range files {
+		name, suffix := GroupFunc(f.Name(), nameTosuffixes)
+		t := BackupTimeIn(f, loc)
+		if name != dbname || suffix == constFileNameHasWrongSuffix || !at.IsZero() && t.After(at) {
+			continue
+		}
+		if IsFullBackupSuffix(suffix) && (full == nil || t.After(fullTime)) {
+			full, fullTime = &files[i], t
+		}
+	}
+	if full == nil {
+		return nil, fmt.Errorf("database %s: %w", dbname, ErrNoFullBackup)
+	}
+	diffs := make(map[string]int) // suffix to index of its newest file
+	logs := []FileInfoWin{}
+	for i, f := range files {
+		name, suffix := GroupFunc(f.Name(), nameTosuffixes)
+		t := BackupTimeIn(f, loc)
+		if name != dbname || suffix == constFileNameHasWrongSuffix || IsFullBackupSuffix(suffix) ||
+			!t.After(fullTime) || !at.IsZero() && t.After(at) {
+			continue
+		}
+		if IsLogBackupSuffix(suffix) {
+			logs = append(logs, f)
+			continue
+		}
+		if j, ok := diffs[suffix]; !ok || t.After(BackupTimeIn(files[j], loc)) {
+			diffs[suffix] = i
+		}
+	}
+
+	ret := []FileInfoWin{*full}
+	baseTime := fullTime // logs are applied after the newest differential backup
+	for _, i := range diffs {
+		ret = append(ret, files[i])
+		if t := BackupTimeIn(files[i], loc); t.After(baseTime) {
+			baseTime = t
+		}
+	}
+	for _, f := range logs {
+		if BackupTimeIn(f, loc).After(baseTime) {
+			ret = append(ret, f)
+		}
+	}
+	sort.SliceStable(ret[1:], func(i, j int) bool {
+		return BackupTimeIn(ret[1+i], loc).Before(BackupTimeIn(ret[1+j], loc))
+	})
+	return ret, nil
+}
@@ -0,0 +1,109 @@
+package dblist
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRestoreChain(t *testing.T) {
+	nameTosuffixes := map[string][]string{"db": {"-FULL.bak", "-differ.bak"}}
+	all := files(
+		"db_2021-08-01T21-00-00-001-FULL.bak",
+		"db_2021-08-02T21-00-00-001-differ.bak",
+		"db_2021-08-03T21-00-00-001-differ.bak",
+		"db_2021-08-08T21-00-00-001-FULL.bak",
+		"db_2021-08-09T21-00-00-001-differ.bak",
+		"db_2021-08-10T21-00-00-001-differ.bak",
+		"db_2021-08-11T21-00-00-001-other.bak",
+		"other_2021-08-10T21-00-00-001-FULL.bak",
+	)
+	names := func(files []FileInfoWin) []string {
+		ret := []string{}
+		for _, f := range files {
+			ret = append(ret, f.Name())
+		}
+		return ret
+	}
+	tests := []struct {
+		name    string
+		at      time.Time
+		want    []string
+		wantErr error
+	}{
+		{"latest", time.Time{}, []string{
+			"db_2021-08-08T21-00-00-001-FULL.bak",
+			"db_2021-08-10T21-00-00-001-differ.bak",
+		}, nil},
+		{"before the second full", mustparse("2021-08-05T00-00-00"), []string{
+			"db_2021-08-01T21-00-00-001-FULL.bak",
+			"db_2021-08-03T21-00-00-001-differ.bak",
+		}, nil},
+		{"at a full", mustparse("2021-08-08T21-00-00"), []string{
+			"db_2021-08-08T21-00-00-001-FULL.bak",
+		}, nil},
+		{"before any full", mustparse("2021-07-01T00-00-00"), nil, ErrNoFullBackup},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RestoreChain(all, "db", nameTosuffixes, tt.at)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RestoreChain() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(names(got), tt.want) {
+				t.Errorf("RestoreChain() = %v, want %v", names(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestRestoreChainLogs(t *testing.T) {
+	nameTosuffixes := map[string][]string{"db": {"-FULL.bak", "-differ.bak", "-log.trn"}}
+	all := files(
+		"db_2021-08-01T21-00-00-001-FULL.bak",
+		"db_2021-08-02T09-00-00-001-log.trn",
+		"db_2021-08-02T21-00-00-001-differ.bak",
+		"db_2021-08-03T09-00-00-001-log.trn",
+		"db_2021-08-03T15-00-00-001-log.trn",
+		"db_2021-08-03T21-00-00-001-log.trn",
+	)
+	names := func(files []FileInfoWin) []string {
+		ret := []string{}
+		for _, f := range files {
+			ret = append(ret, f.Name())
+		}
+		return ret
+	}
+	tests := []struct {
+		name string
+		at   time.Time
+		want []string
+	}{
+		{"latest", time.Time{}, []string{
+			"db_2021-08-01T21-00-00-001-FULL.bak",
+			"db_2021-08-02T21-00-00-001-differ.bak",
+			"db_2021-08-03T09-00-00-001-log.trn",
+			"db_2021-08-03T15-00-00-001-log.trn",
+			"db_2021-08-03T21-00-00-001-log.trn",
+		}},
+		{"logs up to at", mustparse("2021-08-03T16-00-00"), []string{
+			"db_2021-08-01T21-00-00-001-FULL.bak",
+			"db_2021-08-02T21-00-00-001-differ.bak",
+			"db_2021-08-03T09-00-00-001-log.trn",
+			"db_2021-08-03T15-00-00-001-log.trn",
+		}},
+		{"logs after the full", mustparse("2021-08-02T12-00-00"), []string{
+			"db_2021-08-01T21-00-00-001-FULL.bak",
+			"db_2021-08-02T09-00-00-001-log.trn",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RestoreChain(all, "db", nameTosuffixes, tt.at)
+			if err != nil || !reflect.DeepEqual(names(got), tt.want) {
+				t.Errorf("RestoreChain() = %v, %v, want %v", names(got), err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package dblist
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// constFullBackupMark is a part of a suffix that marks full database backups.
+const constFullBackupMark = "FULL"
+
+// constLogBackupMark is a part of a suffix that marks transaction log backups.
+const constLogBackupMark = "-LOG."
+
+// DefaultMaxDeletePercent is a reasonable limit of files that may be deleted from a directory in one run.
+const DefaultMaxDeletePercent = 50
+
+// ErrUnsafeDeletion is returned when a deletion plan violates safety invariants.
+var ErrUnsafeDeletion = errors.New("unsafe deletion plan")
+
+// IsFullBackupSuffix reports whether a file suffix denotes a full database backup.
+// ex. -FULL.bak, -full.rar
+func IsFullBackupSuffix(suffix string) bool {
+	return strings.Contains(strings.ToUpper(suffix), constFullBackupMark)
+}
+
+// IsLogBackupSuffix reports whether a file suffix denotes a transaction log backup.
+// ex. -log.trn, -LOG.bak
+func IsLogBackupSuffix(suffix string) bool {
+	return strings.Contains(strings.ToUpper(suffix), constLogBackupMark)
+}
+
+// GetFilesToDelete returns files that are not selected by GetLastFilesGroupedByFunc.
+// Files not covered by config json file are never returned.
+// files must contain base names of one directory.
+// files slice is not reordered.
+func GetFilesToDelete(files []FileInfoWin, getGroup GrouppingFunc, nameTosuffixes map[string][]string, keepLastNcopies uint) []FileInfoWin {
+	ret := []FileInfoWin{}
+	if len(files) == 0 {
+		return ret
+	}
+	sorted := make([]FileInfoWin, len(files))
+	copy(sorted, files) // GetLastFilesGroupedByFunc sorts its argument
+
+	kept := make(map[string]bool)
+	for _, v := range GetLastFilesGroupedByFunc(sorted, getGroup, nameTosuffixes, keepLastNcopies) {
+		kept[v.Name()] = true
+	}
+	for _, v := range files {
+		n1, n2 := getGroup(v.Name(), nameTosuffixes)
+		if n1 == "" || n2 == constFileNameHasWrongSuffix {
+			continue // not in config json file
+		}
+		if !kept[v.Name()] {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+// CheckDeletionPlan verifies that deleting todelete from files of one directory is safe.
+// Invariants:
+// every file to delete must be covered by config json file;
+// the newest file in each group must remain, a FULL suffix is a group of its own,
+// so the newest FULL backup of every database remains too;
+// no more than maxPercent of files may be deleted (use 100 to disable this check).
+// All violations are reported in one error that wraps ErrUnsafeDeletion.
+func CheckDeletionPlan(files, todelete []FileInfoWin, getGroup GrouppingFunc, nameTosuffixes map[string][]string, maxPercent int) error {
+	if len(todelete) == 0 {
+		return nil
+	}
+	violations := []string{}
+
+	if len(todelete)*100 > maxPercent*len(files) {
+		violations = append(violations,
+			fmt.Sprintf("plan deletes %d of %d files, limit is %d%%", len(todelete), len(files), maxPercent))
+	}
+
+	deleted := make(map[string]bool, len(todelete))
+	for _, v := range todelete {
+		deleted[v.Name()] = true
+		n1, n2 := getGroup(v.Name(), nameTosuffixes)
+		if n1 == "" || n2 == constFileNameHasWrongSuffix {
+			violations = append(violations,
+				fmt.Sprintf("file %s is not covered by config", v.Name()))
+		}
+	}
+
+	sorted := make([]FileInfoWin, len(files))
+	copy(sorted, files)
+	for _, v := range GetLastFilesGroupedByFunc(sorted, getGroup, nameTosuffixes, 1) {
+		if deleted[v.Name()] {
+			n1, n2 := getGroup(v.Name(), nameTosuffixes)
+			violations = append(violations,
+				fmt.Sprintf("file %s is the newest in group %s%s", v.Name(), n1, n2))
+		}
+	}
+
+	if len(violations) != 0 {
+		return fmt.Errorf("%w: %s", ErrUnsafeDeletion, strings.Join(violations, "; "))
+	}
+	return nil
+}
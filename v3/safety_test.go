@@ -0,0 +1,86 @@
+package dblist
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func safetyNameToSuffixes() map[string][]string {
+	nameTosuffixes := make(map[string][]string)
+	nameTosuffixes["зп_в_камин"] = []string{"-FULL.rar", "-differ.rar"}
+	nameTosuffixes["ubd_store_2010"] = []string{"-FULL.bak"}
+	return nameTosuffixes
+}
+
+func TestGetFilesToDelete(t *testing.T) {
+	slice := files(
+		"A_logfile.txt",
+		"зп_в_камин_2021-08-09T10-04-00-750-differ.rar",
+		"зп_в_камин_2021-08-10T10-04-00-717-differ.rar",
+		"зп_в_камин_2021-08-01T17-47-03-337-FULL.rar",
+		"зп_в_камин_2021-08-06T17-47-01-147-FULL.rar",
+		"зп_в_камин_2021-08-06T17-47-01-147-FULL.zip",
+	)
+	want := files(
+		"зп_в_камин_2021-08-09T10-04-00-750-differ.rar",
+		"зп_в_камин_2021-08-01T17-47-03-337-FULL.rar",
+	)
+	got := GetFilesToDelete(slice, GroupFunc, safetyNameToSuffixes(), 1)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetFilesToDelete() = %v, want %v", got, want)
+	}
+	if slice[0].Name() != "A_logfile.txt" {
+		t.Errorf("GetFilesToDelete() reordered its argument")
+	}
+}
+
+func TestCheckDeletionPlan(t *testing.T) {
+	all := files(
+		"зп_в_камин_2021-08-09T10-04-00-750-differ.rar",
+		"зп_в_камин_2021-08-10T10-04-00-717-differ.rar",
+		"зп_в_камин_2021-08-01T17-47-03-337-FULL.rar",
+		"зп_в_камин_2021-08-06T17-47-01-147-FULL.rar",
+		"ubd_store_2010_2018-11-11-FULL.bak",
+		"ubd_store_2010_2018-11-12-FULL.bak",
+		"other_2018-11-12-FULL.bak",
+	)
+	tests := []struct {
+		name       string
+		todelete   []FileInfoWin
+		maxPercent int
+		wantErr    bool
+	}{
+		{"empty plan", files(), 0, false},
+		{"old files",
+			files("зп_в_камин_2021-08-09T10-04-00-750-differ.rar",
+				"зп_в_камин_2021-08-01T17-47-03-337-FULL.rar",
+				"ubd_store_2010_2018-11-11-FULL.bak"),
+			DefaultMaxDeletePercent, false},
+		{"too many files",
+			files("зп_в_камин_2021-08-09T10-04-00-750-differ.rar",
+				"зп_в_камин_2021-08-01T17-47-03-337-FULL.rar",
+				"ubd_store_2010_2018-11-11-FULL.bak"),
+			40, true},
+		{"newest in group",
+			files("зп_в_камин_2021-08-10T10-04-00-717-differ.rar"),
+			100, true},
+		{"not covered",
+			files("other_2018-11-12-FULL.bak"),
+			100, true},
+		{"last FULL", // the newest FULL backup is the newest in its group
+			files("ubd_store_2010_2018-11-12-FULL.bak"),
+			100, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckDeletionPlan(all, tt.todelete, GroupFunc, safetyNameToSuffixes(), tt.maxPercent)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckDeletionPlan() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrUnsafeDeletion) {
+				t.Errorf("CheckDeletionPlan() error = %v, must wrap ErrUnsafeDeletion", err)
+			}
+		})
+	}
+}
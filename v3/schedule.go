@@ -0,0 +1,309 @@
+package dblist
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule gives expected times of backups.
+type Schedule interface {
+	// Next returns the first expected time after t.
+	Next(t time.Time) time.Time
+}
+
+// MissedBackup is an expected backup without a backup file.
+type MissedBackup struct {
+	Line     ConfigLine
+	Expected time.Time // a time the backup was expected at
+	Deadline time.Time // there was no backup file from Expected till Deadline
+}
+
+func (m MissedBackup) String() string {
+	return fmt.Sprintf("%s: missed %s%s backup expected at %s",
+		m.Line.Path, m.Line.Filename, m.Line.Suffix, m.Expected.Format("2006-01-02 15:04"))
+}
+
+// ParseSchedule parses ConfigLine.Schedule.
+// Supported forms are:
+// daily [at HH:MM]
+// weekly <weekday> [at HH:MM], ex. weekly Sunday at 21:00
+// monthly <day of month> [at HH:MM]
+// every <duration>, ex. every 6h or every 2d
+// five fields cron expression: minute hour day-of-month month day-of-week, ex. 0 21 * * 0
+func ParseSchedule(s string) (Schedule, error) {
+	fields := strings.Fields(strings.ToLower(s))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty schedule")
+	}
+	hour, minute := 0, 0
+	if n := len(fields); n >= 3 && fields[n-2] == "at" {
+		t, err := time.Parse("15:04", fields[n-1])
+		if err != nil {
+			return nil, fmt.Errorf("schedule %q has bad time: %w", s, err)
+		}
+		hour, minute = t.Hour(), t.Minute()
+		fields = fields[:n-2]
+	}
+	cron := ""
+	switch {
+	case fields[0] == "daily" && len(fields) == 1:
+		cron = fmt.Sprintf("%d %d * * *", minute, hour)
+	case fields[0] == "weekly" && len(fields) == 2:
+		day, ok := weekdays[fields[1]]
+		if !ok {
+			return nil, fmt.Errorf("schedule %q has bad weekday %q", s, fields[1])
+		}
+		cron = fmt.Sprintf("%d %d * * %d", minute, hour, day)
+	case fields[0] == "monthly" && len(fields) == 2:
+		cron = fmt.Sprintf("%d %d %s * *", minute, hour, fields[1])
+	case fields[0] == "every" && len(fields) == 2:
+		d, err := parseInterval(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("schedule %q has bad interval: %w", s, err)
+		}
+		return intervalSchedule(d), nil
+	case len(fields) == 5:
+		cron = s
+	default:
+		return nil, fmt.Errorf("schedule %q is not recognized", s)
+	}
+	sch, err := parseCron(cron)
+	if err != nil {
+		return nil, fmt.Errorf("schedule %q: %w", s, err)
+	}
+	return sch, nil
+}
+
+var weekdays = map[string]int{
+	"sunday": 0, "monday": 1, "tuesday": 2, "wednesday": 3, "thursday": 4, "friday": 5, "saturday": 6,
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// parseInterval parses time.Duration with additional d unit for days.
+func parseInterval(s string) (time.Duration, error) {
+	var d time.Duration
+	var err error
+	if strings.HasSuffix(s, "d") {
+		var days int
+		days, err = strconv.Atoi(strings.TrimSuffix(s, "d"))
+		d = time.Duration(days) * 24 * time.Hour
+	} else {
+		d, err = time.ParseDuration(s)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if d < time.Minute {
+		return 0, fmt.Errorf("interval %s is less than a minute", s)
+	}
+	return d, nil
+}
+
+// intervalSchedule expects backups at multiples of a duration since zero time.
+// ex. every 6h means 00:00, 06:00, 12:00 and 18:00.
+type intervalSchedule time.Duration
+
+func (i intervalSchedule) Next(t time.Time) time.Time {
+	d := time.Duration(i)
+	return t.Truncate(d).Add(d)
+}
+
+// cronSchedule holds allowed values of every cron field as bit sets.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	anyDom, anyDow                bool
+}
+
+// cronFields are bounds of cron fields.
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+func parseCron(s string) (*cronSchedule, error) {
+	fields := strings.Fields(s)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression must have %d fields", len(cronFields))
+	}
+	sets := make([]uint64, len(fields))
+	for i, f := range fields {
+		set, err := parseCronField(f, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("bad %s field %q: %w", cronFields[i].name, f, err)
+		}
+		sets[i] = set
+	}
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1 // 7 is Sunday too
+	}
+	return &cronSchedule{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		anyDom: fields[2] == "*",
+		anyDow: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField parses lists of *, n, a-b with optional /step.
+func parseCronField(f string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(f, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i != -1 {
+			var err error
+			rng = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("bad step in %q", part)
+			}
+		}
+		lo, hi := min, max
+		if rng != "*" {
+			bounds := strings.SplitN(rng, "-", 2)
+			var err error
+			lo, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return 0, fmt.Errorf("bad value in %q", part)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				hi, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return 0, fmt.Errorf("bad value in %q", part)
+				}
+			} else if step != 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.anyDom || c.anyDow {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func (c *cronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0) // an expression like 0 0 30 2 * never matches
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// CheckSchedule reports expected backups of a config line without backup files.
+// Expected times are taken from line.Schedule in period from..to.
+// A backup is expected from its scheduled time till the next scheduled time,
+// or during grace period if grace is not zero.
+// Backups whose deadline is after to are not reported yet.
+// A line with a pattern of database names is checked for every matched database,
+// Line of a missed backup has the database name and its Modtime then, see EnrichConfigLines.
+// CheckSchedule sets line.Modtime to the newest backup time and line.HasAnyFiles.
+// from and to must be in the same form as times in file names, see WallClock.
+func CheckSchedule(line *ConfigLine, filesByPath map[string][]FileInfoWin, nameTosuffixes map[string][]string, from, to time.Time, grace time.Duration) ([]MissedBackup, error) {
+	files := FilesOfConfigLine(*line, filesByPath, nameTosuffixes)
+	*line = configLineStats(*line, files).ConfigLine
+
+	ret := []MissedBackup{}
+	if line.Schedule == "" {
+		return ret, nil
+	}
+	sch, err := ParseSchedule(line.Schedule)
+	if err != nil {
+		return ret, fmt.Errorf("%s%s: %w", line.Filename, line.Suffix, err)
+	}
+	lines, filesOf := splitByDBName(*line, files, nameTosuffixes)
+	for i := range lines {
+		ret = append(ret, missedBackups(configLineStats(lines[i], filesOf[i]).ConfigLine, filesOf[i], sch, from, to, grace)...)
+	}
+	return ret, nil
+}
+
+// missedBackups reports expected backups of a config line without backup files, see CheckSchedule.
+func missedBackups(line ConfigLine, files []FileInfoWin, sch Schedule, from, to time.Time, grace time.Duration) []MissedBackup {
+	ret := []MissedBackup{}
+	times := make([]time.Time, 0, len(files))
+	for _, f := range files {
+		times = append(times, BackupTimeIn(f, line.Location))
+	}
+
+	for expected := sch.Next(from.Add(-time.Nanosecond)); !expected.IsZero() && expected.Before(to); expected = sch.Next(expected) {
+		deadline := expected.Add(grace)
+		if grace == 0 {
+			deadline = sch.Next(expected)
+		}
+		if deadline.IsZero() || deadline.After(to) {
+			break
+		}
+		found := false
+		for _, t := range times {
+			if !t.Before(expected) && t.Before(deadline) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			ret = append(ret, MissedBackup{Line: line, Expected: expected, Deadline: deadline})
+		}
+	}
+	return ret
+}
+
+// CheckSchedules calls CheckSchedule for every config line.
+// It fills Modtime and HasAnyFiles of config lines in place.
+// Config lines with bad schedules are reported in the returned error, other lines are still checked.
+func CheckSchedules(conf []ConfigLine, filesByPath map[string][]FileInfoWin, from, to time.Time, grace time.Duration) ([]MissedBackup, error) {
+	nameTosuffixes := GetMapFilenameToSuffixes(conf)
+	ret := []MissedBackup{}
+	errs := []string{}
+	for i := range conf {
+		missed, err := CheckSchedule(&conf[i], filesByPath, nameTosuffixes, from, to, grace)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		ret = append(ret, missed...)
+	}
+	if len(errs) != 0 {
+		return ret, fmt.Errorf("bad schedules: %s", strings.Join(errs, "; "))
+	}
+	return ret, nil
+}
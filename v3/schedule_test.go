@@ -0,0 +1,131 @@
+package dblist
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		schedule string
+		after    string
+		want     string
+		wantErr  bool
+	}{
+		{"daily", "2021-08-10T10-04-00", "2021-08-11T00-00-00", false},
+		{"daily at 21:30", "2021-08-10T10-04-00", "2021-08-10T21-30-00", false},
+		{"daily at 21:30", "2021-08-10T21-30-00", "2021-08-11T21-30-00", false},
+		{"weekly Sunday at 21:00", "2021-08-10T10-04-00", "2021-08-15T21-00-00", false},
+		{"monthly 1", "2021-08-10T10-04-00", "2021-09-01T00-00-00", false},
+		{"every 6h", "2021-08-10T10-04-00", "2021-08-10T12-00-00", false},
+		{"every 2d", "2021-08-10T10-04-00", "2021-08-11T00-00-00", false},
+		{"0 21 * * 7", "2021-08-10T10-04-00", "2021-08-15T21-00-00", false},
+		{"*/15 9-17 * * 1-5", "2021-08-14T10-04-00", "2021-08-16T09-00-00", false},
+		{"0 0 13 * 5", "2021-08-01T00-00-00", "2021-08-06T00-00-00", false},
+		{"", "", "", true},
+		{"weekly Funday", "", "", true},
+		{"daily at 25:00", "", "", true},
+		{"every 10s", "", "", true},
+		{"60 * * * *", "", "", true},
+		{"hourly", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.schedule, func(t *testing.T) {
+			sch, err := ParseSchedule(tt.schedule)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSchedule(%q) error = %v, wantErr %v", tt.schedule, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := sch.Next(mustparse(tt.after)); !got.Equal(mustparse(tt.want)) {
+				t.Errorf("ParseSchedule(%q).Next(%s) = %v, want %s", tt.schedule, tt.after, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckSchedules(t *testing.T) {
+	conf := []ConfigLine{
+		{Path: "p", Filename: "db", Suffix: "-FULL.bak", Schedule: "weekly Sunday at 21:00"},
+		{Path: "p", Filename: "db", Suffix: "-differ.bak", Schedule: "daily at 21:00"},
+		{Path: "p", Filename: "nobackups", Suffix: "-FULL.bak"},
+	}
+	filesByPath := map[string][]FileInfoWin{
+		"p": files(
+			"db_2021-08-01T21-00-05-001-FULL.bak",
+			"db_2021-08-08T21-00-05-001-FULL.bak",
+			"db_2021-08-09T21-00-05-001-differ.bak",
+			"db_2021-08-11T21-00-05-001-differ.bak",
+			"db_2021-08-12T21-00-05-001-differ.bak",
+		),
+	}
+	from, to := mustparse("2021-08-01T00-00-00"), mustparse("2021-08-13T12-00-00")
+
+	got, err := CheckSchedules(conf, filesByPath, from, to, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []time.Time{
+		mustparse("2021-08-01T21-00-00"),
+		mustparse("2021-08-02T21-00-00"),
+		mustparse("2021-08-03T21-00-00"),
+		mustparse("2021-08-04T21-00-00"),
+		mustparse("2021-08-05T21-00-00"),
+		mustparse("2021-08-06T21-00-00"),
+		mustparse("2021-08-07T21-00-00"),
+		mustparse("2021-08-08T21-00-00"),
+		mustparse("2021-08-10T21-00-00"),
+	}
+	gotTimes := []time.Time{}
+	for _, m := range got {
+		if m.Line.Suffix != "-differ.bak" {
+			t.Errorf("CheckSchedules() reported %v", m)
+		}
+		gotTimes = append(gotTimes, m.Expected)
+	}
+	if !reflect.DeepEqual(gotTimes, want) {
+		t.Errorf("CheckSchedules() = %v, want %v", gotTimes, want)
+	}
+
+	if !conf[0].HasAnyFiles || !conf[0].Modtime.Equal(mustparse("2021-08-08T21-00-05")) {
+		t.Errorf("CheckSchedules() didn't fill config line %+v", conf[0])
+	}
+	if conf[2].HasAnyFiles || !conf[2].Modtime.IsZero() {
+		t.Errorf("CheckSchedules() filled config line without files %+v", conf[2])
+	}
+
+	got, err = CheckSchedules(conf, filesByPath, from, to, time.Hour)
+	if err != nil || len(got) != 9 {
+		t.Errorf("CheckSchedules() with grace = %v, %v", got, err)
+	}
+
+	conf[2].Schedule = "sometimes"
+	if _, err = CheckSchedules(conf, filesByPath, from, to, 0); err == nil {
+		t.Errorf("CheckSchedules() with bad schedule must fail")
+	}
+}
+
+func TestCheckSchedulesPattern(t *testing.T) {
+	conf := []ConfigLine{{Path: "p", Filename: `^tenant_\d+$`, Suffix: "-FULL.bak", Schedule: "weekly Sunday at 21:00"}}
+	filesByPath := map[string][]FileInfoWin{
+		"p": files(
+			"tenant_1_2021-08-01T21-00-05-001-FULL.bak",
+			"tenant_1_2021-08-08T21-00-05-001-FULL.bak",
+			"tenant_2_2021-08-01T21-00-05-001-FULL.bak",
+		),
+	}
+	// backups of tenant_1 don't hide the missed backup of tenant_2
+	got, err := CheckSchedules(conf, filesByPath, mustparse("2021-08-01T00-00-00"), mustparse("2021-08-13T12-00-00"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Line.Filename != "tenant_2" || !got[0].Expected.Equal(mustparse("2021-08-08T21-00-00")) ||
+		!got[0].Line.Modtime.Equal(mustparse("2021-08-01T21-00-05")) {
+		t.Errorf("CheckSchedules() = %+v, want tenant_2 missed on 2021-08-08", got)
+	}
+	if !conf[0].Modtime.Equal(mustparse("2021-08-08T21-00-05")) {
+		t.Errorf("CheckSchedules() set Modtime %v of the pattern line, want the newest backup", conf[0].Modtime)
+	}
+}